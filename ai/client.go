@@ -0,0 +1,125 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"Hack4Change/logging"
+	"Hack4Change/metrics"
+	"Hack4Change/models"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+const opGenerateSkill = "generate_skill"
+
+// Client talks to the AI service that generates academy skills.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func NewClient(baseURL string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL: baseURL,
+		httpClient: &http.Client{
+			Timeout:   timeout,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+	}
+}
+
+// GenerateSkill asks the AI service for a new skill on the requested topic.
+func (cl *Client) GenerateSkill(ctx context.Context, payload models.GenerateSkillsReq) (*models.SkillRes, error) {
+	start := time.Now()
+	var skill models.SkillRes
+	err := cl.postJSON(ctx, "/ai/generate-skill", payload, &skill)
+	observe(opGenerateSkill, start, err)
+	if err != nil {
+		return nil, err
+	}
+	return &skill, nil
+}
+
+// Ping checks that the AI service accepts connections. Any HTTP response,
+// whatever its status, counts as reachable.
+func (cl *Client) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cl.baseURL+"/", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := cl.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (cl *Client) postJSON(ctx context.Context, path string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return &Error{Reason: "encode", Err: err}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cl.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return &Error{Reason: "request", Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if requestID := logging.RequestID(ctx); requestID != "" {
+		req.Header.Set("X-Request-ID", requestID)
+	}
+
+	resp, err := cl.httpClient.Do(req)
+	if err != nil {
+		reason := "transport"
+		if errors.Is(err, context.DeadlineExceeded) {
+			reason = "timeout"
+		}
+		return &Error{Reason: reason, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return &Error{Reason: "status", Err: fmt.Errorf("ai service returned %s", resp.Status)}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return &Error{Reason: "decode", Err: err}
+	}
+	return nil
+}
+
+// Error is returned for any failed AI call; Reason is a short label also
+// used on the failure metric.
+type Error struct {
+	Reason string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("ai service %s error: %v", e.Reason, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func observe(op string, start time.Time, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+		reason := "unknown"
+		var aiErr *Error
+		if errors.As(err, &aiErr) {
+			reason = aiErr.Reason
+		}
+		metrics.AIRequestFailures.WithLabelValues(op, reason).Inc()
+	}
+	metrics.AIRequestDuration.WithLabelValues(op, outcome).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,168 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Kind classifies an error so the HTTP layer can pick a status code and a
+// stable machine-readable code without inspecting error strings.
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindValidation
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindRateLimited
+	KindPayloadTooLarge
+	KindPreconditionRequired
+)
+
+func (k Kind) Status() int {
+	switch k {
+	case KindValidation:
+		return http.StatusBadRequest
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindRateLimited:
+		return http.StatusTooManyRequests
+	case KindPayloadTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case KindPreconditionRequired:
+		return http.StatusPreconditionRequired
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (k Kind) Code() string {
+	switch k {
+	case KindValidation:
+		return "validation_failed"
+	case KindUnauthorized:
+		return "unauthorized"
+	case KindForbidden:
+		return "forbidden"
+	case KindNotFound:
+		return "not_found"
+	case KindConflict:
+		return "conflict"
+	case KindRateLimited:
+		return "rate_limited"
+	case KindPayloadTooLarge:
+		return "payload_too_large"
+	case KindPreconditionRequired:
+		return "precondition_required"
+	default:
+		return "internal_error"
+	}
+}
+
+// Error is the typed error passed from storage and handlers to the error
+// middleware. Message is safe to show to clients; Err is kept for logging.
+// Details, when set, is sent to the client as structured context, such as
+// the current state of a resource after a conflict.
+type Error struct {
+	Kind    Kind
+	Message string
+	Fields  map[string]string
+	Details any
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithField attaches a field-level detail and returns the same error.
+func (e *Error) WithField(field, msg string) *Error {
+	if e.Fields == nil {
+		e.Fields = map[string]string{}
+	}
+	e.Fields[field] = msg
+	return e
+}
+
+// WithDetails attaches client-visible structured details and returns the
+// same error.
+func (e *Error) WithDetails(details any) *Error {
+	e.Details = details
+	return e
+}
+
+func New(kind Kind, msg string) *Error {
+	return &Error{Kind: kind, Message: msg}
+}
+
+func Wrap(kind Kind, err error, msg string) *Error {
+	return &Error{Kind: kind, Message: msg, Err: err}
+}
+
+func NotFound(msg string) *Error {
+	return New(KindNotFound, msg)
+}
+
+func Conflict(msg string) *Error {
+	return New(KindConflict, msg)
+}
+
+func Validation(msg string) *Error {
+	return New(KindValidation, msg)
+}
+
+func Forbidden(msg string) *Error {
+	return New(KindForbidden, msg)
+}
+
+func Unauthorized(msg string) *Error {
+	return New(KindUnauthorized, msg)
+}
+
+func RateLimited(msg string) *Error {
+	return New(KindRateLimited, msg)
+}
+
+func PayloadTooLarge(msg string) *Error {
+	return New(KindPayloadTooLarge, msg)
+}
+
+func PreconditionRequired(msg string) *Error {
+	return New(KindPreconditionRequired, msg)
+}
+
+func Internal(err error) *Error {
+	return Wrap(KindInternal, err, "internal server error")
+}
+
+// From returns the typed error in err's chain, or wraps err as an internal
+// error when none is present.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(err)
+}
+
+// Is reports whether err carries an apperrors.Error of the given kind.
+func Is(err error, kind Kind) bool {
+	var appErr *Error
+	return errors.As(err, &appErr) && appErr.Kind == kind
+}
@@ -0,0 +1,71 @@
+package background
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Group runs background jobs that must stop and finish before the process
+// exits. Jobs receive a context that is cancelled when Shutdown starts.
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewGroup() *Group {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Group{ctx: ctx, cancel: cancel}
+}
+
+// Go runs fn in its own goroutine, recovering and logging panics.
+func (g *Group) Go(name string, fn func(ctx context.Context)) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				slog.Error("Background job panicked", "job", name, "panic", r)
+			}
+		}()
+		fn(g.ctx)
+	}()
+}
+
+// Every runs fn every interval until the group shuts down. A run in
+// progress is allowed to finish.
+func (g *Group) Every(name string, interval time.Duration, fn func(ctx context.Context) error) {
+	g.Go(name, func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := fn(ctx); err != nil && ctx.Err() == nil {
+					slog.Error("Background job failed", "job", name, "error", err)
+				}
+			}
+		}
+	})
+}
+
+// Shutdown cancels the jobs' context and waits for them to return or for
+// ctx to expire.
+func (g *Group) Shutdown(ctx context.Context) error {
+	g.cancel()
+	finished := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,164 @@
+// Package cli implements the hack4change command: the HTTP server plus the
+// operational tasks that used to be exposed as HTTP routes.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"text/tabwriter"
+
+	"Hack4Change/config"
+	"Hack4Change/database"
+	"Hack4Change/logging"
+	"Hack4Change/server"
+	"Hack4Change/validation"
+)
+
+// command is one subcommand. run receives the arguments after the command
+// name.
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(ctx context.Context, env *env, args []string) error
+}
+
+var commands = []command{
+	{"serve", "", "Run the HTTP API (the default when no command is given)", runServe},
+	{"migrate", "up | down [-steps N] [-force] | status", "Apply, roll back or list schema migrations", runMigrate},
+	{"seed", "[-force]", "Insert a demo user and space for local development", runSeed},
+	{"create-admin", "-email E -username U [-password P]", "Create an admin account, or promote an existing one", runCreateAdmin},
+	{"reset-password", "-email E [-password P]", "Set a new password for an account", runResetPassword},
+	{"export-user", "-email E | -id ID [-out FILE]", "Write an account with all its spaces and files as JSON", runExportUser},
+	{"git-export", "-space ID -repo PATH [-branch B] [-history]", "Commit a space, optionally with its revision history, to a git repository or bundle", runGitExport},
+	{"git-import", "-repo PATH -owner EMAIL [-ref REF] [-name N] [-description D]", "Create a space from a commit of a git repository or bundle", runGitImport},
+	{"delete-user", "-email E | -id ID", "Soft-delete an account so it can no longer sign in", runDeleteUser},
+	{"purge-deleted", "[-older-than DURATION] [-dry-run]", "Permanently remove accounts soft-deleted long enough ago", runPurgeDeleted},
+}
+
+// errUsage reports bad arguments; Run prints the usage after it.
+var errUsage = errors.New("invalid usage")
+
+// env is shared by every command. The database is opened on first use so
+// commands that don't need it, like help, work without one.
+type env struct {
+	cfg    config.Config
+	stdout io.Writer
+	stderr io.Writer
+	db     *database.PostQreSQLCon
+}
+
+func (e *env) database() (*database.PostQreSQLCon, error) {
+	if e.db != nil {
+		return e.db, nil
+	}
+	db, err := database.ConnectPostgreSQL(e.cfg.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+	e.db = db
+	return db, nil
+}
+
+// Run executes the command named by args[0], defaulting to serve. The
+// server logs to stdout; every other command logs to stderr so its output
+// can be piped.
+func Run(ctx context.Context, cfg config.Config, args []string, stdout, stderr io.Writer) error {
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" || name == "-h" || name == "--help" {
+		printUsage(stdout)
+		return nil
+	}
+
+	cmd, ok := lookup(name)
+	if !ok {
+		printUsage(stderr)
+		return fmt.Errorf("unknown command %q", name)
+	}
+
+	logOut := stderr
+	if cmd.name == "serve" {
+		logOut = stdout
+	}
+	slog.SetDefault(logging.New(cfg, logOut))
+
+	e := &env{cfg: cfg, stdout: stdout, stderr: stderr}
+	defer func() {
+		if e.db != nil {
+			e.db.Close()
+		}
+	}()
+
+	err := cmd.run(ctx, e, args)
+	if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(stderr, "usage: hack4change %s %s\n", cmd.name, cmd.args)
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+	}
+	return err
+}
+
+func lookup(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: hack4change <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.name, cmd.summary)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Configuration is read from the same environment variables as the server.")
+}
+
+// newFlags returns a flag set that reports errors instead of exiting.
+func newFlags(e *env, name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	return fs
+}
+
+// parseFlags parses args and rejects leftover positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w: unexpected arguments %s", errUsage, strings.Join(fs.Args(), " "))
+	}
+	return nil
+}
+
+func runServe(ctx context.Context, e *env, args []string) error {
+	if err := parseFlags(newFlags(e, "serve"), args); err != nil {
+		return err
+	}
+	return server.Run(ctx, e.cfg)
+}
+
+// setupValidation loads the request validators so commands can check their
+// input with the same rules as the API.
+func setupValidation() error {
+	if err := validation.Setup(); err != nil {
+		return fmt.Errorf("setting up validation: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"Hack4Change/models"
+	"Hack4Change/spacearchive"
+	"Hack4Change/spacegit"
+	"Hack4Change/validation"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+)
+
+func runGitExport(ctx context.Context, e *env, args []string) error {
+	fs := newFlags(e, "git-export")
+	spaceID := fs.String("space", "", "ID of the space to export")
+	repo := fs.String("repo", "", "repository to write to, created bare if missing; a path ending in .bundle writes a bundle file")
+	branch := fs.String("branch", spacegit.DefaultBranch, "branch to commit to")
+	history := fs.Bool("history", false, "commit every file revision instead of only the current state")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *spaceID == "" || *repo == "" {
+		return fmt.Errorf("%w: -space and -repo are required", errUsage)
+	}
+
+	db, err := e.database()
+	if err != nil {
+		return err
+	}
+	project, err := db.FetchProject(ctx, *spaceID)
+	if err != nil {
+		return err
+	}
+	owner, err := db.FetchUserDetails(ctx, project.OwnerID)
+	if err != nil {
+		return err
+	}
+	tree, err := db.GetProjectTree(ctx, project.ProjectID, true)
+	if err != nil {
+		return err
+	}
+	author := spacegit.Signature{Name: owner.Username, Email: owner.Email}
+
+	var snapshots []spacegit.Snapshot
+	if *history {
+		revisions, err := db.ListProjectRevisions(ctx, project.ProjectID)
+		if err != nil {
+			return err
+		}
+		snapshots = spacegit.History(tree, revisions, author)
+	}
+	// The current state goes last: after a history replay it normally
+	// matches the final revision and is not committed again.
+	snapshots = append(snapshots, spacegit.Snapshot{
+		Files:   spacearchive.FromProject(tree).Files,
+		Message: "Export " + project.ProjectName,
+		Author:  author,
+		When:    project.UpdatedAt,
+	})
+
+	result, err := spacegit.Export(*repo, *branch, snapshots)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stdout, "Exported %s to %s on branch %s: %d new commits, tip %s.\n",
+		project.ProjectID, *repo, result.Branch, result.Commits, result.Commit)
+	return nil
+}
+
+func runGitImport(ctx context.Context, e *env, args []string) error {
+	fs := newFlags(e, "git-import")
+	repo := fs.String("repo", "", "repository directory or bundle file to read")
+	ref := fs.String("ref", spacegit.DefaultRef, "branch, tag or commit to import")
+	ownerEmail := fs.String("owner", "", "email of the account that will own the space")
+	name := fs.String("name", "", "space name; defaults to the repository's name")
+	description := fs.String("description", "", "space description")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *repo == "" || *ownerEmail == "" {
+		return fmt.Errorf("%w: -repo and -owner are required", errUsage)
+	}
+
+	req := models.ImportSpaceReq{ProjectName: *name, ProjectDescription: *description}
+	if req.ProjectName == "" {
+		req.ProjectName = repoName(*repo)
+	}
+	if err := setupValidation(); err != nil {
+		return err
+	}
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return validationFailure(validation.Error(err))
+	}
+
+	db, err := e.database()
+	if err != nil {
+		return err
+	}
+	ownerID, err := db.FetchUserIdByEmail(ctx, *ownerEmail)
+	if err != nil {
+		return err
+	}
+
+	imported, err := spacegit.Import(*repo, *ref, spacearchive.Limits{
+		MaxEntries:    e.cfg.ImportMaxEntries,
+		MaxTotalBytes: e.cfg.ImportMaxTotalBytes,
+		MaxFileBytes:  e.cfg.MaxFileBodyBytes,
+	})
+	if err != nil {
+		return err
+	}
+	tree := imported.Tree
+	if len(tree.Files) == 0 {
+		return fmt.Errorf("%s at %s has no text files to import", *repo, *ref)
+	}
+
+	project := models.ProjectDetails{
+		ProjectID:          uuid.New().String(),
+		OwnerID:            ownerID,
+		ProjectName:        req.ProjectName,
+		ProjectDescription: req.ProjectDescription,
+	}
+	if err := db.ImportProject(ctx, project, tree, ownerID); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(e.stdout, "Imported %s at %s (%s) as space %s: %d folders, %d files.\n",
+		*repo, *ref, imported.Commit, project.ProjectID, len(tree.Dirs), len(tree.Files))
+	for _, entry := range tree.Skipped {
+		fmt.Fprintf(e.stdout, "  skipped %s (%s)\n", entry.Path, entry.Reason)
+	}
+	return nil
+}
+
+// repoName derives a space name from a repository path: the last element
+// without a .git or .bundle suffix.
+func repoName(repo string) string {
+	name := filepath.Base(strings.TrimRight(repo, `/\`))
+	name = strings.TrimSuffix(name, spacegit.BundleExt)
+	return strings.TrimSuffix(name, ".git")
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"Hack4Change/database"
+)
+
+func runMigrate(ctx context.Context, e *env, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: missing subcommand", errUsage)
+	}
+	sub, args := args[0], args[1:]
+
+	fs := newFlags(e, "migrate "+sub)
+	steps, force := 1, false
+	if sub == "down" {
+		fs.IntVar(&steps, "steps", 1, "number of migrations to roll back")
+		fs.BoolVar(&force, "force", false, "allow rolling back in production")
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	db, err := e.database()
+	if err != nil {
+		return err
+	}
+
+	switch sub {
+	case "up":
+		applied, err := db.MigrateUp(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(e.stdout, "Schema is up to date.")
+			return nil
+		}
+		for _, m := range applied {
+			fmt.Fprintf(e.stdout, "Applied %d %s\n", m.Version, m.Name)
+		}
+	case "down":
+		if steps < 1 {
+			return fmt.Errorf("%w: -steps must be at least 1", errUsage)
+		}
+		if e.cfg.IsProduction() && !force {
+			return fmt.Errorf("refusing to roll back migrations in production without -force")
+		}
+		reverted, err := db.MigrateDown(ctx, steps)
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Fprintln(e.stdout, "Nothing to roll back.")
+			return nil
+		}
+		for _, m := range reverted {
+			fmt.Fprintf(e.stdout, "Rolled back %d %s\n", m.Version, m.Name)
+		}
+	case "status":
+		status, err := db.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+		printMigrationStatus(e, status)
+	default:
+		return fmt.Errorf("%w: unknown migrate subcommand %q", errUsage, sub)
+	}
+	return nil
+}
+
+func printMigrationStatus(e *env, status []database.MigrationStatus) {
+	tw := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+	for _, m := range status {
+		appliedAt := "pending"
+		if m.AppliedAt != nil {
+			appliedAt = m.AppliedAt.Local().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", m.Version, m.Name, appliedAt)
+	}
+	tw.Flush()
+}
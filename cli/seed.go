@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"Hack4Change/apperrors"
+	"Hack4Change/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	seedEmail    = "demo@hack4change.dev"
+	seedUsername = "demo"
+	seedPassword = "demo-password"
+)
+
+// seedFiles is the sample space created for the demo user, keyed by folder
+// ("" for the root).
+var seedFiles = map[string]map[string]string{
+	"": {
+		"README.md": "# Sample space\n\nCreated by `hack4change seed`.\n",
+	},
+	"src": {
+		"main.py": "def main():\n    print(\"Hello, Hack4Change!\")\n\n\nif __name__ == \"__main__\":\n    main()\n",
+	},
+}
+
+func runSeed(ctx context.Context, e *env, args []string) error {
+	fs := newFlags(e, "seed")
+	force := fs.Bool("force", false, "allow seeding in production")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if e.cfg.IsProduction() && !*force {
+		return fmt.Errorf("refusing to seed a production database without -force")
+	}
+
+	db, err := e.database()
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.FetchUserIdByEmail(ctx, seedEmail); err == nil {
+		fmt.Fprintf(e.stdout, "Demo user %s already exists, nothing to do.\n", seedEmail)
+		return nil
+	} else if !apperrors.Is(err, apperrors.KindNotFound) {
+		return err
+	}
+
+	userID, err := createUser(ctx, db, models.CreateAccountReq{
+		Username:        seedUsername,
+		Email:           seedEmail,
+		FirstName:       "Demo",
+		LastName:        "User",
+		Password:        seedPassword,
+		ConfirmPassword: seedPassword,
+	}, models.RoleUser)
+	if err != nil {
+		return err
+	}
+
+	projectID := uuid.New().String()
+	if err := db.InsertProject(ctx, models.ProjectDetails{
+		ProjectID:          projectID,
+		OwnerID:            userID,
+		ProjectName:        "Sample space",
+		ProjectDescription: "A small project to try the editor with",
+	}); err != nil {
+		return err
+	}
+
+	for folderName, files := range seedFiles {
+		var parentID *string
+		if folderName != "" {
+			folderID := uuid.New().String()
+			if err := db.InsertFolder(ctx, models.Folder{ID: folderID, ProjectID: projectID, FolderName: folderName}); err != nil {
+				return err
+			}
+			parentID = &folderID
+		}
+		for name, content := range files {
+			file := models.File{ID: uuid.New().String(), ProjectID: projectID, ParentFolderId: parentID, FileName: name, FileContent: content}
+			if err := db.InsertFile(ctx, file, userID); err != nil {
+				return err
+			}
+		}
+	}
+
+	fmt.Fprintf(e.stdout, "Seeded demo user %s (password %q) with space %s.\n", seedEmail, seedPassword, projectID)
+	return nil
+}
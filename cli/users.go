@@ -0,0 +1,298 @@
+package cli
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"Hack4Change/apperrors"
+	"Hack4Change/database"
+	"Hack4Change/helpers"
+	"Hack4Change/models"
+	"Hack4Change/validation"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+)
+
+func runCreateAdmin(ctx context.Context, e *env, args []string) error {
+	fs := newFlags(e, "create-admin")
+	email := fs.String("email", "", "account email")
+	username := fs.String("username", "", "username, used when the account is created")
+	password := fs.String("password", "", "password; a random one is generated and printed if empty")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return fmt.Errorf("%w: -email is required", errUsage)
+	}
+
+	db, err := e.database()
+	if err != nil {
+		return err
+	}
+
+	userID, err := db.FetchUserIdByEmail(ctx, *email)
+	switch {
+	case err == nil:
+		if err := db.SetUserRole(ctx, userID, models.RoleAdmin); err != nil {
+			return err
+		}
+		fmt.Fprintf(e.stdout, "Promoted existing user %s (%s) to admin.\n", *email, userID)
+		return nil
+	case !apperrors.Is(err, apperrors.KindNotFound):
+		return err
+	}
+
+	generated := *password == ""
+	if generated {
+		if *password, err = randomPassword(); err != nil {
+			return err
+		}
+	}
+	userID, err = createUser(ctx, db, models.CreateAccountReq{
+		Username:        *username,
+		Email:           *email,
+		Password:        *password,
+		ConfirmPassword: *password,
+	}, models.RoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(e.stdout, "Created admin %s (%s).\n", *email, userID)
+	if generated {
+		fmt.Fprintf(e.stdout, "Generated password: %s\n", *password)
+	}
+	return nil
+}
+
+func runResetPassword(ctx context.Context, e *env, args []string) error {
+	fs := newFlags(e, "reset-password")
+	email := fs.String("email", "", "account email")
+	password := fs.String("password", "", "new password; a random one is generated and printed if empty")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return fmt.Errorf("%w: -email is required", errUsage)
+	}
+
+	generated := *password == ""
+	if generated {
+		var err error
+		if *password, err = randomPassword(); err != nil {
+			return err
+		}
+	}
+	if n := len(*password); n < 8 || n > 128 {
+		return fmt.Errorf("password must be between 8 and 128 characters")
+	}
+
+	db, err := e.database()
+	if err != nil {
+		return err
+	}
+	userID, err := db.FetchUserIdByEmail(ctx, *email)
+	if err != nil {
+		return err
+	}
+	hash, err := helpers.HashPassword(*password)
+	if err != nil {
+		return err
+	}
+	if err := db.UpdatePasswordHash(ctx, userID, hash); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(e.stdout, "Password reset for %s.\n", *email)
+	if generated {
+		fmt.Fprintf(e.stdout, "Generated password: %s\n", *password)
+	}
+	return nil
+}
+
+// userExport is the document written by export-user. Password hashes are
+// never included.
+type userExport struct {
+	ExportedAt time.Time           `json:"exported_at"`
+	User       *models.UserDetails `json:"user"`
+	Projects   []projectExport     `json:"projects"`
+}
+
+type projectExport struct {
+	models.ProjectDetails
+	Contents models.ProjectContents `json:"contents"`
+}
+
+func runExportUser(ctx context.Context, e *env, args []string) error {
+	fs := newFlags(e, "export-user")
+	email := fs.String("email", "", "account email")
+	id := fs.String("id", "", "account ID")
+	out := fs.String("out", "", "file to write; stdout if empty")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if (*email == "") == (*id == "") {
+		return fmt.Errorf("%w: exactly one of -email or -id is required", errUsage)
+	}
+
+	db, err := e.database()
+	if err != nil {
+		return err
+	}
+	userID := *id
+	if userID == "" {
+		if userID, err = db.FetchUserIdByEmail(ctx, *email); err != nil {
+			return err
+		}
+	}
+
+	export := userExport{ExportedAt: time.Now().UTC(), Projects: []projectExport{}}
+	if export.User, err = db.FetchUserDetails(ctx, userID); err != nil {
+		return err
+	}
+	projects, err := db.FetchProjectsByUserId(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, project := range projects {
+		contents, err := db.GetProjectStructure(ctx, project.ProjectID)
+		if err != nil {
+			return fmt.Errorf("exporting project %s: %w", project.ProjectID, err)
+		}
+		export.Projects = append(export.Projects, projectExport{ProjectDetails: project, Contents: contents})
+	}
+
+	w := e.stdout
+	if *out != "" {
+		f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(export); err != nil {
+		return err
+	}
+	if *out != "" {
+		fmt.Fprintf(e.stderr, "Exported %s with %d spaces to %s.\n", userID, len(export.Projects), *out)
+	}
+	return nil
+}
+
+func runDeleteUser(ctx context.Context, e *env, args []string) error {
+	fs := newFlags(e, "delete-user")
+	email := fs.String("email", "", "account email")
+	id := fs.String("id", "", "account ID")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if (*email == "") == (*id == "") {
+		return fmt.Errorf("%w: exactly one of -email or -id is required", errUsage)
+	}
+
+	db, err := e.database()
+	if err != nil {
+		return err
+	}
+	userID := *id
+	if userID == "" {
+		if userID, err = db.FetchUserIdByEmail(ctx, *email); err != nil {
+			return err
+		}
+	}
+	if err := db.SoftDeleteUser(ctx, userID); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(e.stdout, "Deleted %s; purge-deleted removes it for good.\n", userID)
+	return nil
+}
+
+func runPurgeDeleted(ctx context.Context, e *env, args []string) error {
+	fs := newFlags(e, "purge-deleted")
+	olderThan := fs.Duration("older-than", 30*24*time.Hour, "only purge accounts deleted at least this long ago")
+	dryRun := fs.Bool("dry-run", false, "report what would be purged without deleting")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *olderThan < 0 {
+		return fmt.Errorf("%w: -older-than must not be negative", errUsage)
+	}
+
+	db, err := e.database()
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-*olderThan)
+
+	if *dryRun {
+		n, err := db.CountDeletedUsers(ctx, cutoff)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(e.stdout, "Would purge %d accounts deleted before %s.\n", n, cutoff.Format(time.RFC3339))
+		return nil
+	}
+	n, err := db.PurgeDeletedUsers(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stdout, "Purged %d accounts deleted before %s.\n", n, cutoff.Format(time.RFC3339))
+	return nil
+}
+
+// createUser validates req with the API's rules and inserts the account with
+// the given role, returning its ID.
+func createUser(ctx context.Context, db *database.PostQreSQLCon, req models.CreateAccountReq, role string) (string, error) {
+	if err := setupValidation(); err != nil {
+		return "", err
+	}
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return "", validationFailure(validation.Error(err))
+	}
+
+	hash, err := helpers.HashPassword(req.Password)
+	if err != nil {
+		return "", err
+	}
+	user := models.UserDetails{
+		ID:        uuid.New().String(),
+		Username:  req.Username,
+		Email:     req.Email,
+		Phone:     req.Phone,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Role:      role,
+	}
+	if err := db.InsertUser(ctx, user, hash); err != nil {
+		return "", err
+	}
+	return user.ID, nil
+}
+
+// validationFailure spells out field errors, which the API would have sent
+// as JSON, for the terminal.
+func validationFailure(err *apperrors.Error) error {
+	msg := err.Message
+	for field, problem := range err.Fields {
+		msg += fmt.Sprintf("\n  %s: %s", field, problem)
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+func randomPassword() (string, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
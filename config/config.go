@@ -0,0 +1,217 @@
+package config
+
+import (
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+)
+
+// Config holds settings read from the environment at startup.
+type Config struct {
+	Env          string
+	LogLevel     string
+	AIServiceURL string
+	AITimeout    time.Duration
+
+	// DatabaseURL is a lib/pq connection string or URL. When DATABASE_URL is
+	// unset it is assembled from the DB_* variables.
+	DatabaseURL string
+
+	HTTPAddr              string
+	HTTPReadHeaderTimeout time.Duration
+	HTTPReadTimeout       time.Duration
+	HTTPWriteTimeout      time.Duration
+	HTTPIdleTimeout       time.Duration
+	ShutdownTimeout       time.Duration
+
+	// LegacyDeprecatedAt and LegacySunset are advertised on the unversioned
+	// aliases of the v1 API.
+	LegacyDeprecatedAt time.Time
+	LegacySunset       time.Time
+
+	// RateLimitStore is "memory" or "postgres". The RateLimit* limits use
+	// the "<requests>/<period>" syntax, or "off".
+	RateLimitStore      string
+	RateLimitAuth       string
+	RateLimitAPI        string
+	RateLimitAIGenerate string
+
+	// CORSAllowedOrigins lists the browser origins allowed to call the API;
+	// "*" allows any.
+	CORSAllowedOrigins   []string
+	CORSAllowCredentials bool
+	CORSMaxAge           time.Duration
+	HSTSMaxAge           time.Duration
+
+	// MaxBodyBytes caps JSON request bodies; MaxFileBodyBytes applies instead
+	// on routes that carry file content.
+	MaxBodyBytes     int64
+	MaxFileBodyBytes int64
+
+	// ImportMaxBytes caps an uploaded space archive. Once extracted it may
+	// hold at most ImportMaxEntries entries and ImportMaxTotalBytes of
+	// files, none larger than MaxFileBodyBytes.
+	ImportMaxBytes      int64
+	ImportMaxEntries    int
+	ImportMaxTotalBytes int64
+
+	// CompressMinBytes is the smallest response body worth compressing;
+	// only media types in CompressContentTypes are compressed.
+	CompressMinBytes     int
+	CompressContentTypes []string
+
+	// AdminResetSecret signs the confirmation tokens of the admin data reset.
+	// When unset a random secret is generated at startup, which only works
+	// with a single instance.
+	AdminResetSecret string
+
+	// IdempotencyKeyTTL is how long a stored Idempotency-Key response is
+	// replayed before the key may be reused.
+	IdempotencyKeyTTL time.Duration
+
+	ServiceName       string
+	TracesExporter    string
+	TracesSampleRatio float64
+}
+
+// Load reads the configuration from environment variables, falling back to
+// development defaults.
+func Load() Config {
+	return Config{
+		Env:          strings.ToLower(getEnv("APP_ENV", EnvDevelopment)),
+		LogLevel:     getEnv("LOG_LEVEL", "info"),
+		AIServiceURL: getEnv("AI_SERVICE_URL", "http://localhost:5868"),
+		AITimeout:    getDuration("AI_TIMEOUT", 60*time.Second),
+
+		DatabaseURL: getEnv("DATABASE_URL", databaseURLFromParts()),
+
+		HTTPAddr:              getEnv("HTTP_ADDR", ":7563"),
+		HTTPReadHeaderTimeout: getDuration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+		HTTPReadTimeout:       getDuration("HTTP_READ_TIMEOUT", 30*time.Second),
+		HTTPWriteTimeout:      getDuration("HTTP_WRITE_TIMEOUT", 90*time.Second),
+		HTTPIdleTimeout:       getDuration("HTTP_IDLE_TIMEOUT", 120*time.Second),
+		ShutdownTimeout:       getDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+
+		LegacyDeprecatedAt: getTime("API_LEGACY_DEPRECATED_AT", time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)),
+		LegacySunset:       getTime("API_LEGACY_SUNSET", time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC)),
+
+		RateLimitStore:      strings.ToLower(getEnv("RATE_LIMIT_STORE", "memory")),
+		RateLimitAuth:       getEnv("RATE_LIMIT_AUTH", "10/1m"),
+		RateLimitAPI:        getEnv("RATE_LIMIT_API", "300/1m"),
+		RateLimitAIGenerate: getEnv("RATE_LIMIT_AI_GENERATE", "5/1m"),
+
+		CORSAllowedOrigins:   getList("CORS_ALLOWED_ORIGINS", []string{"http://localhost:3000", "http://localhost:5173"}),
+		CORSAllowCredentials: getBool("CORS_ALLOW_CREDENTIALS", false),
+		CORSMaxAge:           getDuration("CORS_MAX_AGE", 10*time.Minute),
+		HSTSMaxAge:           getDuration("HSTS_MAX_AGE", 365*24*time.Hour),
+
+		MaxBodyBytes:     getInt64("MAX_BODY_BYTES", 1<<20),
+		MaxFileBodyBytes: getInt64("MAX_FILE_BODY_BYTES", 5<<20),
+
+		ImportMaxBytes:      getInt64("IMPORT_MAX_BYTES", 20<<20),
+		ImportMaxEntries:    getInt("IMPORT_MAX_ENTRIES", 5000),
+		ImportMaxTotalBytes: getInt64("IMPORT_MAX_TOTAL_BYTES", 50<<20),
+
+		CompressMinBytes:     getInt("COMPRESS_MIN_BYTES", 1024),
+		CompressContentTypes: getList("COMPRESS_CONTENT_TYPES", []string{"application/json", "application/yaml", "text/html", "text/plain", "text/css", "application/javascript"}),
+
+		AdminResetSecret: os.Getenv("ADMIN_RESET_SECRET"),
+
+		IdempotencyKeyTTL: getDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+
+		ServiceName:       getEnv("OTEL_SERVICE_NAME", "hack4change-backend"),
+		TracesExporter:    strings.ToLower(getEnv("OTEL_TRACES_EXPORTER", "none")),
+		TracesSampleRatio: getFloat("OTEL_TRACES_SAMPLER_ARG", 1),
+	}
+}
+
+func (c Config) IsProduction() bool {
+	return c.Env == EnvProduction
+}
+
+func databaseURLFromParts() string {
+	sslMode := "disable"
+	if strings.ToLower(getEnv("APP_ENV", EnvDevelopment)) == EnvProduction {
+		sslMode = "require"
+	}
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(getEnv("DB_USER", "postgres"), os.Getenv("DB_PASSWORD")),
+		Host:     net.JoinHostPort(getEnv("DB_HOST", "localhost"), getEnv("DB_PORT", "5432")),
+		Path:     "/" + getEnv("DB_NAME", "postgres"),
+		RawQuery: url.Values{"sslmode": {getEnv("DB_SSLMODE", sslMode)}}.Encode(),
+	}
+	return u.String()
+}
+
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return d
+	}
+	return fallback
+}
+
+func getFloat(key string, fallback float64) float64 {
+	if f, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return f
+	}
+	return fallback
+}
+
+func getInt(key string, fallback int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return n
+	}
+	return fallback
+}
+
+func getInt64(key string, fallback int64) int64 {
+	if n, err := strconv.ParseInt(os.Getenv(key), 10, 64); err == nil {
+		return n
+	}
+	return fallback
+}
+
+func getBool(key string, fallback bool) bool {
+	if b, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return b
+	}
+	return fallback
+}
+
+// getList reads a comma-separated list, dropping empty entries.
+func getList(key string, fallback []string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok || strings.TrimSpace(value) == "" {
+		return fallback
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getTime(key string, fallback time.Time) time.Time {
+	if t, err := time.Parse(time.RFC3339, os.Getenv(key)); err == nil {
+		return t
+	}
+	return fallback
+}
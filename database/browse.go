@@ -0,0 +1,118 @@
+package database
+
+import (
+	"Hack4Change/apperrors"
+	"Hack4Change/filetype"
+	"Hack4Change/models"
+	"context"
+)
+
+// fileMetaColumns reads a file's size without shipping its content;
+// octet_length of a TOASTed value does not need to decompress it.
+const fileMetaColumns = `file_uid, parent_folder_id, file_name, octet_length(file_content), version, updated_at`
+
+func (pg *PostQreSQLCon) queryFileMeta(ctx context.Context, query string, args ...any) ([]models.FileMeta, error) {
+	rows, err := pg.dbCon.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, mapError(err, "file")
+	}
+	defer rows.Close()
+
+	files := []models.FileMeta{}
+	for rows.Next() {
+		var file models.FileMeta
+		if err := rows.Scan(&file.ID, &file.ParentFolderId, &file.FileName, &file.SizeBytes, &file.Version, &file.UpdatedAt); err != nil {
+			return nil, mapError(err, "file")
+		}
+		file.Language = filetype.Language(file.FileName)
+		files = append(files, file)
+	}
+	return files, mapError(rows.Err(), "file")
+}
+
+// GetProjectMetaTree returns the project as nested folders holding file
+// metadata only: names, sizes and languages, but no content.
+func (pg *PostQreSQLCon) GetProjectMetaTree(ctx context.Context, projectID string) (models.MetaTree, error) {
+	ctx, done := instrument(ctx, "GetProjectMetaTree")
+	defer done()
+
+	folders, err := pg.loadFolders(ctx, projectID)
+	if err != nil {
+		return models.MetaTree{}, err
+	}
+	files, err := pg.queryFileMeta(ctx, `SELECT `+fileMetaColumns+` FROM files WHERE project_id = $1 ORDER BY file_name`, projectID)
+	if err != nil {
+		return models.MetaTree{}, err
+	}
+	return buildMetaTree(projectID, folders, files), nil
+}
+
+// buildMetaTree nests folders, given parents before children, and files
+// under their parents, as buildTree does for full files.
+func buildMetaTree(projectID string, folders []models.Folder, files []models.FileMeta) models.MetaTree {
+	tree := models.MetaTree{ProjectID: projectID}
+	tree.Folders, tree.Files = nestFolders(folders, files,
+		func(file models.FileMeta) *string { return file.ParentFolderId },
+		func(folder models.Folder, subfolders []models.MetaTreeFolder, files []models.FileMeta) models.MetaTreeFolder {
+			return models.MetaTreeFolder{
+				FolderMeta: models.FolderMeta{
+					ID:             folder.ID,
+					ParentFolderId: folder.ParentFolderId,
+					FolderName:     folder.FolderName,
+					ChildCount:     len(subfolders) + len(files),
+					UpdatedAt:      folder.UpdatedAt,
+				},
+				Folders: subfolders,
+				Files:   files,
+			}
+		})
+	return tree
+}
+
+// ListFolderChildren returns the subfolders and files directly inside
+// folderID, or inside the project root when folderID is nil, so a client
+// can expand a large tree one folder at a time.
+func (pg *PostQreSQLCon) ListFolderChildren(ctx context.Context, projectID string, folderID *string) (models.FolderChildren, error) {
+	ctx, done := instrument(ctx, "ListFolderChildren")
+	defer done()
+
+	listing := models.FolderChildren{FolderID: folderID, Folders: []models.FolderMeta{}}
+	// Separate predicates for the root and a folder keep the parent index
+	// usable; IS NOT DISTINCT FROM would not be.
+	parentFilter, args := `parent_folder_id IS NULL`, []any{projectID}
+	if folderID != nil {
+		var exists bool
+		err := pg.dbCon.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM folders WHERE folder_uid = $1 AND project_id = $2)`, *folderID, projectID).Scan(&exists)
+		if err != nil {
+			return listing, mapError(err, "folder")
+		}
+		if !exists {
+			return listing, apperrors.NotFound("folder not found")
+		}
+		parentFilter, args = `parent_folder_id = $2`, append(args, *folderID)
+	}
+
+	query := `SELECT f.folder_uid, f.parent_folder_id, f.folder_name, f.updated_at,
+                     (SELECT COUNT(*) FROM folders c WHERE c.parent_folder_id = f.folder_uid)
+                   + (SELECT COUNT(*) FROM files c WHERE c.parent_folder_id = f.folder_uid)
+              FROM folders f WHERE f.project_id = $1 AND f.` + parentFilter + ` ORDER BY f.folder_name`
+	rows, err := pg.dbCon.QueryContext(ctx, query, args...)
+	if err != nil {
+		return listing, mapError(err, "folder")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var folder models.FolderMeta
+		if err := rows.Scan(&folder.ID, &folder.ParentFolderId, &folder.FolderName, &folder.UpdatedAt, &folder.ChildCount); err != nil {
+			return listing, mapError(err, "folder")
+		}
+		listing.Folders = append(listing.Folders, folder)
+	}
+	if err := rows.Err(); err != nil {
+		return listing, mapError(err, "folder")
+	}
+
+	listing.Files, err = pg.queryFileMeta(ctx, `SELECT `+fileMetaColumns+` FROM files
+                                                WHERE project_id = $1 AND `+parentFilter+` ORDER BY file_name`, args...)
+	return listing, err
+}
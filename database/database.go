@@ -1,6 +1,8 @@
 package database
 
 import (
+	"Hack4Change/metrics"
+	"context"
 	"fmt"
 	"log/slog"
 
@@ -9,9 +11,10 @@ import (
 	_ "github.com/lib/pq"
 )
 
-func ConnectPostgreSQL() (*PostQreSQLCon, error) {
-
-	db, err := sqlx.Connect("postgres", "user=postgres dbname=postgres sslmode=require password=aymaan132 host=database.c9oigwacc6k7.ap-south-1.rds.amazonaws.com")
+// ConnectPostgreSQL opens the connection pool for dsn and checks that the
+// database answers.
+func ConnectPostgreSQL(dsn string) (*PostQreSQLCon, error) {
+	db, err := sqlx.Connect("postgres", dsn)
 	if err != nil {
 		slog.Error("Failed to open database connection", slog.String("error", err.Error()))
 		return nil, fmt.Errorf("failed to open database connection: %w", err)
@@ -22,9 +25,25 @@ func ConnectPostgreSQL() (*PostQreSQLCon, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
+	if err := metrics.RegisterDBStats(db.DB, "postgres"); err != nil {
+		slog.Warn("Failed to register database pool metrics", slog.String("error", err.Error()))
+	}
+
 	slog.Info("Successfully connected to the database")
 
 	return &PostQreSQLCon{
 		dbCon: db,
 	}, nil
 }
+
+// Ping checks that the database is reachable.
+func (pg *PostQreSQLCon) Ping(ctx context.Context) error {
+	ctx, done := instrument(ctx, "Ping")
+	defer done()
+	return pg.dbCon.PingContext(ctx)
+}
+
+// Close closes the connection pool, waiting for in-flight queries to finish.
+func (pg *PostQreSQLCon) Close() error {
+	return pg.dbCon.Close()
+}
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"Hack4Change/apperrors"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Postgres SQLSTATE codes we translate into domain errors.
+const (
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
+	pqNotNullViolation    = "23502"
+	pqInvalidTextRepr     = "22P02"
+	pqStringTooLong       = "22001"
+)
+
+// uniqueFields maps unique constraint names to the request field they guard.
+var uniqueFields = map[string]string{
+	"users_email_key":         "email",
+	"users_username_key":      "username",
+	"files_parent_name_key":   "file_name",
+	"folders_parent_name_key": "folder_name",
+}
+
+// mapError converts driver errors into apperrors so handlers never have to
+// look at SQL error strings. entity names the row being read or written.
+func mapError(err error, entity string) error {
+	if err == nil {
+		return nil
+	}
+	var appErr *apperrors.Error
+	if errors.As(err, &appErr) {
+		return err
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperrors.Wrap(apperrors.KindNotFound, err, entity+" not found")
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return apperrors.Internal(err)
+	}
+	switch pqErr.Code {
+	case pqUniqueViolation:
+		e := apperrors.Wrap(apperrors.KindConflict, err, entity+" already exists")
+		if field, ok := uniqueFields[pqErr.Constraint]; ok {
+			e.WithField(field, "is already taken")
+		}
+		return e
+	case pqForeignKeyViolation:
+		return apperrors.Wrap(apperrors.KindValidation, err, "referenced "+referencedEntity(pqErr)+" does not exist")
+	case pqNotNullViolation:
+		e := apperrors.Wrap(apperrors.KindValidation, err, "missing required value")
+		if pqErr.Column != "" {
+			e.WithField(pqErr.Column, "is required")
+		}
+		return e
+	case pqInvalidTextRepr:
+		return apperrors.Wrap(apperrors.KindValidation, err, "malformed identifier")
+	case pqStringTooLong:
+		return apperrors.Wrap(apperrors.KindValidation, err, "value too long")
+	}
+	return apperrors.Internal(err)
+}
+
+// expectRows maps the result of an UPDATE or DELETE that must touch a row,
+// turning zero affected rows into a not-found error for entity.
+func expectRows(res sql.Result, err error, entity string) error {
+	if err != nil {
+		return mapError(err, entity)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return mapError(err, entity)
+	}
+	if n == 0 {
+		return apperrors.NotFound(entity + " not found")
+	}
+	return nil
+}
+
+// referencedEntity guesses the referenced table from a foreign key constraint
+// name such as files_parent_folder_id_fkey.
+func referencedEntity(pqErr *pq.Error) string {
+	name := strings.TrimSuffix(pqErr.Constraint, "_fkey")
+	switch {
+	case strings.HasSuffix(name, "parent_folder_id"):
+		return "folder"
+	case strings.HasSuffix(name, "project_id"):
+		return "project"
+	case strings.HasSuffix(name, "user_id"):
+		return "user"
+	}
+	return "record"
+}
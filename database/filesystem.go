@@ -0,0 +1,381 @@
+package database
+
+import (
+	"Hack4Change/apperrors"
+	"Hack4Change/logging"
+	"Hack4Change/models"
+	"context"
+	"database/sql"
+	"strconv"
+)
+
+const (
+	fileColumns   = `file_uid, project_id, parent_folder_id, file_name, file_content, version, created_at, updated_at`
+	folderColumns = `folder_uid, project_id, folder_name, parent_folder_id, created_at, updated_at`
+)
+
+// queryer is satisfied by both the connection pool and a transaction.
+type queryer interface {
+	execer
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func scanFile(row *sql.Row, file *models.File) error {
+	return row.Scan(&file.ID, &file.ProjectID, &file.ParentFolderId, &file.FileName, &file.FileContent, &file.Version, &file.CreatedAt, &file.UpdatedAt)
+}
+
+func scanFolder(row *sql.Row, folder *models.Folder) error {
+	return row.Scan(&folder.ID, &folder.ProjectID, &folder.FolderName, &folder.ParentFolderId, &folder.CreatedAt, &folder.UpdatedAt)
+}
+
+// nullableID maps a missing or empty folder ID to SQL NULL, the root.
+func nullableID(id *string) any {
+	if id == nil || *id == "" {
+		return nil
+	}
+	return *id
+}
+
+// lockProjectTree serialises structural changes within a project for the
+// rest of the transaction, so two concurrent moves cannot build a cycle
+// that neither would have built alone.
+func lockProjectTree(ctx context.Context, db execer, projectID string) error {
+	_, err := db.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, projectID)
+	return mapError(err, "project")
+}
+
+// checkParentFolder rejects a parent folder that belongs to another project.
+// A nil or empty parent is the project root and always valid.
+func checkParentFolder(ctx context.Context, db queryer, projectID string, parentID *string) error {
+	if nullableID(parentID) == nil {
+		return nil
+	}
+	var ok bool
+	query := `SELECT EXISTS (SELECT 1 FROM folders WHERE folder_uid = $1 AND project_id = $2)`
+	if err := db.QueryRowContext(ctx, query, *parentID, projectID).Scan(&ok); err != nil {
+		return mapError(err, "folder")
+	}
+	if !ok {
+		return apperrors.Validation("Parent folder is not in this space").
+			WithField("parent_folder_id", "must be a folder in this space")
+	}
+	return nil
+}
+
+// checkNameFree rejects name when a file or folder other than exceptID
+// already uses it in parentID. Callers hold the project tree lock, so the
+// check also covers the file/folder clash the unique indexes cannot see.
+// field names the request field reported in the conflict.
+func checkNameFree(ctx context.Context, db queryer, projectID string, parentID *string, name, exceptID, field string) error {
+	var taken bool
+	query := `SELECT EXISTS (SELECT 1 FROM files
+                               WHERE project_id = $1 AND parent_folder_id IS NOT DISTINCT FROM $2
+                                 AND file_name = $3 AND file_uid::text <> $4)
+                  OR EXISTS (SELECT 1 FROM folders
+                               WHERE project_id = $1 AND parent_folder_id IS NOT DISTINCT FROM $2
+                                 AND folder_name = $3 AND folder_uid::text <> $4)`
+	if err := db.QueryRowContext(ctx, query, projectID, nullableID(parentID), name, exceptID).Scan(&taken); err != nil {
+		return mapError(err, "file")
+	}
+	if taken {
+		return apperrors.Conflict("A file or folder named "+strconv.Quote(name)+" already exists there").
+			WithField(field, "is already used in this folder")
+	}
+	return nil
+}
+
+// insertFile creates a file and its first revision. The caller holds the
+// project tree lock.
+func insertFile(ctx context.Context, tx queryer, file models.File, authorID string) error {
+	if err := checkParentFolder(ctx, tx, file.ProjectID, file.ParentFolderId); err != nil {
+		return err
+	}
+	if err := checkNameFree(ctx, tx, file.ProjectID, file.ParentFolderId, file.FileName, "", "file_name"); err != nil {
+		return err
+	}
+	query := `INSERT INTO files (file_uid, project_id, parent_folder_id, file_name, file_content, created_at, updated_at)
+              VALUES ($1, $2, $3, $4, $5, NOW(), NOW())`
+	_, err := tx.ExecContext(ctx, query, file.ID, file.ProjectID, nullableID(file.ParentFolderId), file.FileName, file.FileContent)
+	if err != nil {
+		logging.FromContext(ctx).Error("InsertFile: Error inserting file", "fileID", file.ID, "error", err)
+		return mapError(err, "file")
+	}
+	if err := insertRevision(ctx, tx, file.ID, 1, authorID, file.FileContent, nil); err != nil {
+		return err
+	}
+	return touchAncestors(ctx, tx, file.ProjectID, file.ParentFolderId)
+}
+
+// insertFolder creates a folder. The caller holds the project tree lock.
+func insertFolder(ctx context.Context, tx queryer, folder models.Folder) error {
+	if err := checkParentFolder(ctx, tx, folder.ProjectID, folder.ParentFolderId); err != nil {
+		return err
+	}
+	if err := checkNameFree(ctx, tx, folder.ProjectID, folder.ParentFolderId, folder.FolderName, "", "folder_name"); err != nil {
+		return err
+	}
+	query := `INSERT INTO folders (folder_uid, project_id, folder_name, parent_folder_id, created_at, updated_at)
+              VALUES ($1, $2, $3, $4, NOW(), NOW())`
+	_, err := tx.ExecContext(ctx, query, folder.ID, folder.ProjectID, folder.FolderName, nullableID(folder.ParentFolderId))
+	if err != nil {
+		logging.FromContext(ctx).Error("InsertFolder: Error inserting folder", "folderID", folder.ID, "error", err)
+		return mapError(err, "folder")
+	}
+	return touchAncestors(ctx, tx, folder.ProjectID, folder.ParentFolderId)
+}
+
+// touchAncestors bumps updated_at on each given folder, every folder above
+// it and the project, so a change anywhere shows on the way up to the root.
+func touchAncestors(ctx context.Context, db execer, projectID string, folderIDs ...*string) error {
+	for _, id := range folderIDs {
+		if nullableID(id) == nil {
+			continue
+		}
+		query := `WITH RECURSIVE chain AS (
+                      SELECT folder_uid, parent_folder_id FROM folders WHERE folder_uid = $1 AND project_id = $2
+                      UNION
+                      SELECT f.folder_uid, f.parent_folder_id FROM folders f JOIN chain c ON f.folder_uid = c.parent_folder_id
+                  )
+                  UPDATE folders SET updated_at = NOW() WHERE folder_uid IN (SELECT folder_uid FROM chain)`
+		if _, err := db.ExecContext(ctx, query, *id, projectID); err != nil {
+			return mapError(err, "folder")
+		}
+	}
+	_, err := db.ExecContext(ctx, `UPDATE projects SET updated_at = NOW() WHERE project_uid = $1`, projectID)
+	return mapError(err, "project")
+}
+
+// RenameFile changes a file's name without moving it.
+func (pg *PostQreSQLCon) RenameFile(ctx context.Context, projectID, fileID, name string) (models.File, error) {
+	ctx, done := instrument(ctx, "RenameFile")
+	defer done()
+
+	var file models.File
+	tx, err := pg.dbCon.BeginTxx(ctx, nil)
+	if err != nil {
+		return file, mapError(err, "file")
+	}
+	defer tx.Rollback()
+
+	if err := lockProjectTree(ctx, tx, projectID); err != nil {
+		return file, err
+	}
+	var parent *string
+	err = tx.QueryRowContext(ctx, `SELECT parent_folder_id FROM files WHERE file_uid = $1 AND project_id = $2`, fileID, projectID).Scan(&parent)
+	if err != nil {
+		return file, mapError(err, "file")
+	}
+	if err := checkNameFree(ctx, tx, projectID, parent, name, fileID, "name"); err != nil {
+		return file, err
+	}
+
+	query := `UPDATE files SET file_name = $1, updated_at = NOW()
+              WHERE file_uid = $2 AND project_id = $3
+              RETURNING ` + fileColumns
+	if err := scanFile(tx.QueryRowContext(ctx, query, name, fileID, projectID), &file); err != nil {
+		return file, mapError(err, "file")
+	}
+	if err := touchAncestors(ctx, tx, projectID, file.ParentFolderId); err != nil {
+		return file, err
+	}
+	return file, mapError(tx.Commit(), "file")
+}
+
+// MoveFile moves a file into parentID, or to the root when parentID is nil.
+func (pg *PostQreSQLCon) MoveFile(ctx context.Context, projectID, fileID string, parentID *string) (models.File, error) {
+	ctx, done := instrument(ctx, "MoveFile")
+	defer done()
+
+	var file models.File
+	tx, err := pg.dbCon.BeginTxx(ctx, nil)
+	if err != nil {
+		return file, mapError(err, "file")
+	}
+	defer tx.Rollback()
+
+	if err := lockProjectTree(ctx, tx, projectID); err != nil {
+		return file, err
+	}
+	if err := checkParentFolder(ctx, tx, projectID, parentID); err != nil {
+		return file, err
+	}
+	var oldParent *string
+	var name string
+	err = tx.QueryRowContext(ctx, `SELECT parent_folder_id, file_name FROM files WHERE file_uid = $1 AND project_id = $2`, fileID, projectID).Scan(&oldParent, &name)
+	if err != nil {
+		return file, mapError(err, "file")
+	}
+	if err := checkNameFree(ctx, tx, projectID, parentID, name, fileID, "parent_folder_id"); err != nil {
+		return file, err
+	}
+
+	query := `UPDATE files SET parent_folder_id = $1, updated_at = NOW()
+              WHERE file_uid = $2 AND project_id = $3
+              RETURNING ` + fileColumns
+	if err := scanFile(tx.QueryRowContext(ctx, query, nullableID(parentID), fileID, projectID), &file); err != nil {
+		return file, mapError(err, "file")
+	}
+	if err := touchAncestors(ctx, tx, projectID, oldParent, file.ParentFolderId); err != nil {
+		return file, err
+	}
+	return file, mapError(tx.Commit(), "file")
+}
+
+// DeleteFile deletes a file and its revision history.
+func (pg *PostQreSQLCon) DeleteFile(ctx context.Context, projectID, fileID string) error {
+	ctx, done := instrument(ctx, "DeleteFile")
+	defer done()
+
+	tx, err := pg.dbCon.BeginTxx(ctx, nil)
+	if err != nil {
+		return mapError(err, "file")
+	}
+	defer tx.Rollback()
+
+	var parent *string
+	query := `DELETE FROM files WHERE file_uid = $1 AND project_id = $2 RETURNING parent_folder_id`
+	if err := tx.QueryRowContext(ctx, query, fileID, projectID).Scan(&parent); err != nil {
+		return mapError(err, "file")
+	}
+	if err := touchAncestors(ctx, tx, projectID, parent); err != nil {
+		return err
+	}
+	return mapError(tx.Commit(), "file")
+}
+
+// RenameFolder changes a folder's name without moving it.
+func (pg *PostQreSQLCon) RenameFolder(ctx context.Context, projectID, folderID, name string) (models.Folder, error) {
+	ctx, done := instrument(ctx, "RenameFolder")
+	defer done()
+
+	var folder models.Folder
+	tx, err := pg.dbCon.BeginTxx(ctx, nil)
+	if err != nil {
+		return folder, mapError(err, "folder")
+	}
+	defer tx.Rollback()
+
+	if err := lockProjectTree(ctx, tx, projectID); err != nil {
+		return folder, err
+	}
+	var parent *string
+	err = tx.QueryRowContext(ctx, `SELECT parent_folder_id FROM folders WHERE folder_uid = $1 AND project_id = $2`, folderID, projectID).Scan(&parent)
+	if err != nil {
+		return folder, mapError(err, "folder")
+	}
+	if err := checkNameFree(ctx, tx, projectID, parent, name, folderID, "name"); err != nil {
+		return folder, err
+	}
+
+	query := `UPDATE folders SET folder_name = $1, updated_at = NOW()
+              WHERE folder_uid = $2 AND project_id = $3
+              RETURNING ` + folderColumns
+	if err := scanFolder(tx.QueryRowContext(ctx, query, name, folderID, projectID), &folder); err != nil {
+		return folder, mapError(err, "folder")
+	}
+	if err := touchAncestors(ctx, tx, projectID, folder.ParentFolderId); err != nil {
+		return folder, err
+	}
+	return folder, mapError(tx.Commit(), "folder")
+}
+
+// MoveFolder moves a folder, with everything in it, into parentID or to the
+// root when parentID is nil. Moving a folder into itself or one of its own
+// descendants is rejected.
+func (pg *PostQreSQLCon) MoveFolder(ctx context.Context, projectID, folderID string, parentID *string) (models.Folder, error) {
+	ctx, done := instrument(ctx, "MoveFolder")
+	defer done()
+
+	var folder models.Folder
+	tx, err := pg.dbCon.BeginTxx(ctx, nil)
+	if err != nil {
+		return folder, mapError(err, "folder")
+	}
+	defer tx.Rollback()
+
+	if err := lockProjectTree(ctx, tx, projectID); err != nil {
+		return folder, err
+	}
+	if err := checkParentFolder(ctx, tx, projectID, parentID); err != nil {
+		return folder, err
+	}
+	var oldParent *string
+	var name string
+	err = tx.QueryRowContext(ctx, `SELECT parent_folder_id, folder_name FROM folders WHERE folder_uid = $1 AND project_id = $2`, folderID, projectID).Scan(&oldParent, &name)
+	if err != nil {
+		return folder, mapError(err, "folder")
+	}
+
+	if nullableID(parentID) != nil {
+		// Walk up from the new parent; meeting the folder being moved means
+		// the move would make it its own ancestor.
+		var cycle bool
+		query := `WITH RECURSIVE ancestors AS (
+                      SELECT folder_uid, parent_folder_id FROM folders WHERE folder_uid = $1
+                      UNION
+                      SELECT f.folder_uid, f.parent_folder_id FROM folders f JOIN ancestors a ON f.folder_uid = a.parent_folder_id
+                  )
+                  SELECT EXISTS (SELECT 1 FROM ancestors WHERE folder_uid = $2)`
+		if err := tx.QueryRowContext(ctx, query, *parentID, folderID).Scan(&cycle); err != nil {
+			return folder, mapError(err, "folder")
+		}
+		if cycle {
+			return folder, apperrors.Validation("A folder cannot be moved into itself or its own subfolders").
+				WithField("parent_folder_id", "must not be the folder or one of its descendants")
+		}
+	}
+	if err := checkNameFree(ctx, tx, projectID, parentID, name, folderID, "parent_folder_id"); err != nil {
+		return folder, err
+	}
+
+	query := `UPDATE folders SET parent_folder_id = $1, updated_at = NOW()
+              WHERE folder_uid = $2 AND project_id = $3
+              RETURNING ` + folderColumns
+	if err := scanFolder(tx.QueryRowContext(ctx, query, nullableID(parentID), folderID, projectID), &folder); err != nil {
+		return folder, mapError(err, "folder")
+	}
+	if err := touchAncestors(ctx, tx, projectID, oldParent, folder.ParentFolderId); err != nil {
+		return folder, err
+	}
+	return folder, mapError(tx.Commit(), "folder")
+}
+
+// DeleteFolder deletes a folder with all of its subfolders and files, and
+// reports how many of each went.
+func (pg *PostQreSQLCon) DeleteFolder(ctx context.Context, projectID, folderID string) (folders, files int64, err error) {
+	ctx, done := instrument(ctx, "DeleteFolder")
+	defer done()
+
+	tx, err := pg.dbCon.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, 0, mapError(err, "folder")
+	}
+	defer tx.Rollback()
+
+	if err := lockProjectTree(ctx, tx, projectID); err != nil {
+		return 0, 0, err
+	}
+	query := `WITH RECURSIVE subtree AS (
+                  SELECT folder_uid FROM folders WHERE folder_uid = $1 AND project_id = $2
+                  UNION
+                  SELECT f.folder_uid FROM folders f JOIN subtree s ON f.parent_folder_id = s.folder_uid
+              )
+              SELECT (SELECT COUNT(*) FROM subtree),
+                     (SELECT COUNT(*) FROM files WHERE parent_folder_id IN (SELECT folder_uid FROM subtree))`
+	if err := tx.QueryRowContext(ctx, query, folderID, projectID).Scan(&folders, &files); err != nil {
+		return 0, 0, mapError(err, "folder")
+	}
+	if folders == 0 {
+		return 0, 0, apperrors.NotFound("folder not found")
+	}
+
+	// The parent_folder_id foreign keys cascade down the subtree.
+	var parent *string
+	if err := tx.QueryRowContext(ctx, `DELETE FROM folders WHERE folder_uid = $1 RETURNING parent_folder_id`, folderID).Scan(&parent); err != nil {
+		return 0, 0, mapError(err, "folder")
+	}
+	if err := touchAncestors(ctx, tx, projectID, parent); err != nil {
+		return 0, 0, err
+	}
+	return folders, files, mapError(tx.Commit(), "folder")
+}
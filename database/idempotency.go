@@ -0,0 +1,92 @@
+package database
+
+import (
+	"Hack4Change/apperrors"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// IdempotencyRecord is the stored outcome of the first request made with an
+// Idempotency-Key. Until the request finishes Completed is false and the
+// response fields are empty.
+type IdempotencyRecord struct {
+	UserID       string `db:"user_id"`
+	Key          string `db:"idempotency_key"`
+	RequestHash  string `db:"request_hash"`
+	StatusCode   int    `db:"status_code"`
+	ContentType  string `db:"content_type"`
+	ResponseBody []byte `db:"response_body"`
+	Completed    bool   `db:"completed"`
+}
+
+// ReserveIdempotencyKey claims (userID, key) for a new request. It returns
+// nil when the key was free, or had expired after ttl and was taken over;
+// otherwise it returns the record already holding the key.
+func (pg *PostQreSQLCon) ReserveIdempotencyKey(ctx context.Context, userID, key, requestHash string, ttl time.Duration) (*IdempotencyRecord, error) {
+	ctx, done := instrument(ctx, "ReserveIdempotencyKey")
+	defer done()
+
+	insert := `INSERT INTO idempotency_keys (user_id, idempotency_key, request_hash, created_at)
+               VALUES ($1, $2, $3, NOW())
+               ON CONFLICT (user_id, idempotency_key) DO UPDATE
+               SET request_hash = EXCLUDED.request_hash, status_code = NULL, content_type = NULL,
+                   response_body = NULL, created_at = NOW(), completed_at = NULL
+               WHERE idempotency_keys.created_at < NOW() - make_interval(secs => $4)`
+	res, err := pg.dbCon.ExecContext(ctx, insert, userID, key, requestHash, ttl.Seconds())
+	if err != nil {
+		return nil, mapError(err, "idempotency key")
+	}
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		return nil, nil
+	}
+
+	var record IdempotencyRecord
+	query := `SELECT user_id, idempotency_key, request_hash, COALESCE(status_code, 0) AS status_code,
+                     COALESCE(content_type, '') AS content_type, COALESCE(response_body, ''::bytea) AS response_body,
+                     completed_at IS NOT NULL AS completed
+              FROM idempotency_keys WHERE user_id = $1 AND idempotency_key = $2`
+	if err := pg.dbCon.GetContext(ctx, &record, query, userID, key); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// The holder failed and released the key between our two statements.
+			return nil, apperrors.Conflict("A request with this Idempotency-Key was just released, retry it")
+		}
+		return nil, mapError(err, "idempotency key")
+	}
+	return &record, nil
+}
+
+// CompleteIdempotencyKey stores the response of the request holding the key
+// so retries can replay it.
+func (pg *PostQreSQLCon) CompleteIdempotencyKey(ctx context.Context, userID, key string, statusCode int, contentType string, body []byte) error {
+	ctx, done := instrument(ctx, "CompleteIdempotencyKey")
+	defer done()
+
+	query := `UPDATE idempotency_keys
+              SET status_code = $3, content_type = $4, response_body = $5, completed_at = NOW()
+              WHERE user_id = $1 AND idempotency_key = $2`
+	_, err := pg.dbCon.ExecContext(ctx, query, userID, key, statusCode, contentType, body)
+	return mapError(err, "idempotency key")
+}
+
+// ReleaseIdempotencyKey frees a key whose request failed without a response
+// worth replaying, so the client can retry with the same key.
+func (pg *PostQreSQLCon) ReleaseIdempotencyKey(ctx context.Context, userID, key string) error {
+	ctx, done := instrument(ctx, "ReleaseIdempotencyKey")
+	defer done()
+
+	query := `DELETE FROM idempotency_keys WHERE user_id = $1 AND idempotency_key = $2 AND completed_at IS NULL`
+	_, err := pg.dbCon.ExecContext(ctx, query, userID, key)
+	return mapError(err, "idempotency key")
+}
+
+// DeleteExpiredIdempotencyKeys removes keys older than ttl.
+func (pg *PostQreSQLCon) DeleteExpiredIdempotencyKeys(ctx context.Context, ttl time.Duration) error {
+	ctx, done := instrument(ctx, "DeleteExpiredIdempotencyKeys")
+	defer done()
+
+	query := `DELETE FROM idempotency_keys WHERE created_at < NOW() - make_interval(secs => $1)`
+	_, err := pg.dbCon.ExecContext(ctx, query, ttl.Seconds())
+	return mapError(err, "idempotency key")
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"Hack4Change/models"
+	"Hack4Change/spacearchive"
+	"context"
+	"path"
+
+	"github.com/google/uuid"
+)
+
+// ImportProject creates project with the folders and files of an extracted
+// archive in one transaction, so a failed import leaves nothing behind.
+// Each file starts its history with a revision by authorID.
+func (pg *PostQreSQLCon) ImportProject(ctx context.Context, project models.ProjectDetails, tree spacearchive.Tree, authorID string) error {
+	ctx, done := instrument(ctx, "ImportProject")
+	defer done()
+
+	tx, err := pg.dbCon.BeginTxx(ctx, nil)
+	if err != nil {
+		return mapError(err, "project")
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO projects (project_uid, user_id, project_name, project_description, created_at, updated_at)
+              VALUES ($1, $2, $3, $4, NOW(), NOW())`
+	if _, err := tx.ExecContext(ctx, query, project.ProjectID, project.OwnerID, project.ProjectName, project.ProjectDescription); err != nil {
+		return mapError(err, "project")
+	}
+
+	// The project is new and the archive's paths are already unique, so
+	// rows go straight in without the per-insert name checks.
+	insertFolder, err := tx.PreparexContext(ctx, `INSERT INTO folders (folder_uid, project_id, folder_name, parent_folder_id, created_at, updated_at)
+                                                  VALUES ($1, $2, $3, $4, NOW(), NOW())`)
+	if err != nil {
+		return mapError(err, "folder")
+	}
+	defer insertFolder.Close()
+	folderIDs := make(map[string]string, len(tree.Dirs))
+	for _, dir := range tree.Dirs {
+		id := uuid.New().String()
+		if _, err := insertFolder.ExecContext(ctx, id, project.ProjectID, path.Base(dir), parentID(folderIDs, dir)); err != nil {
+			return mapError(err, "folder")
+		}
+		folderIDs[dir] = id
+	}
+
+	insertFile, err := tx.PreparexContext(ctx, `INSERT INTO files (file_uid, project_id, parent_folder_id, file_name, file_content, created_at, updated_at)
+                                                VALUES ($1, $2, $3, $4, $5, NOW(), NOW())`)
+	if err != nil {
+		return mapError(err, "file")
+	}
+	defer insertFile.Close()
+	for _, file := range tree.Files {
+		id := uuid.New().String()
+		if _, err := insertFile.ExecContext(ctx, id, project.ProjectID, parentID(folderIDs, file.Path), path.Base(file.Path), file.Content); err != nil {
+			return mapError(err, "file")
+		}
+		if err := insertRevision(ctx, tx, id, 1, authorID, file.Content, nil); err != nil {
+			return err
+		}
+	}
+	return mapError(tx.Commit(), "project")
+}
+
+// parentID looks up the folder holding p, nil for the root.
+func parentID(folderIDs map[string]string, p string) any {
+	if dir := path.Dir(p); dir != "." {
+		return folderIDs[dir]
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"Hack4Change/metrics"
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("Hack4Change/database")
+
+// instrument starts a span and a latency timer for a storage method. Use as
+//
+//	ctx, done := instrument(ctx, "InsertUser")
+//	defer done()
+func instrument(ctx context.Context, method string) (context.Context, func()) {
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, "db."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation.name", method),
+		),
+	)
+	return ctx, func() {
+		metrics.DBQueryDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+		span.End()
+	}
+}
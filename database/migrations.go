@@ -0,0 +1,499 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"Hack4Change/logging"
+)
+
+// migrationLockID is the pg_advisory_lock key that keeps two instances from
+// migrating the same database concurrently.
+const migrationLockID = 0x4861636b // "Hack"
+
+// migration is one versioned schema change. Up and Down run in a single
+// transaction together with the schema_migrations bookkeeping.
+type migration struct {
+	Version int
+	Name    string
+	Up      []string
+	Down    []string
+}
+
+// MigrationStatus reports whether a known migration has been applied.
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+// migrations must stay sorted by Version; never edit one that has shipped,
+// add a new one instead.
+var migrations = []migration{
+	{
+		Version: 1,
+		Name:    "initial_schema",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS users (
+				user_uid UUID PRIMARY KEY,
+				username VARCHAR(32) NOT NULL UNIQUE,
+				email VARCHAR(255) NOT NULL UNIQUE,
+				phone VARCHAR(20),
+				first_name VARCHAR(32),
+				last_name VARCHAR(32),
+				password_hash TEXT NOT NULL,
+				social_accounts JSONB,
+				badges JSONB,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+			);`,
+			`CREATE TABLE IF NOT EXISTS socials (
+				socials_uid UUID PRIMARY KEY,
+				user_id UUID REFERENCES users(user_uid) ON DELETE CASCADE,
+				github VARCHAR(255),
+				linkedin VARCHAR(255),
+				instagram VARCHAR(255),
+				noobs_social VARCHAR(255)
+			);`,
+			`CREATE TABLE IF NOT EXISTS projects (
+				project_uid UUID PRIMARY KEY,
+				user_id UUID REFERENCES users(user_uid) ON DELETE CASCADE,
+				project_name VARCHAR(50) NOT NULL,
+				project_description VARCHAR(255),
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+			);`,
+			`CREATE TABLE IF NOT EXISTS folders (
+				folder_uid UUID PRIMARY KEY,
+				project_id UUID REFERENCES projects(project_uid) ON DELETE CASCADE,
+				folder_name VARCHAR(255) NOT NULL,
+				parent_folder_id UUID REFERENCES folders(folder_uid) ON DELETE SET NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+			);`,
+			`CREATE TABLE IF NOT EXISTS files (
+				file_uid UUID PRIMARY KEY,
+				project_id UUID REFERENCES projects(project_uid) ON DELETE CASCADE,
+				file_name VARCHAR(255) NOT NULL,
+				file_content TEXT NOT NULL,
+				parent_folder_id UUID REFERENCES folders(folder_uid) ON DELETE SET NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+			);`,
+			`CREATE TABLE IF NOT EXISTS skills (
+				skill_uid UUID PRIMARY KEY,
+				topic VARCHAR(255) NOT NULL,
+				intro TEXT NOT NULL,
+				data JSONB NOT NULL,
+				user_ids TEXT[] NOT NULL
+			);`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS files CASCADE;`,
+			`DROP TABLE IF EXISTS folders CASCADE;`,
+			`DROP TABLE IF EXISTS projects CASCADE;`,
+			`DROP TABLE IF EXISTS socials CASCADE;`,
+			`DROP TABLE IF EXISTS skills CASCADE;`,
+			`DROP TABLE IF EXISTS users CASCADE;`,
+		},
+	},
+	{
+		Version: 2,
+		Name:    "rate_limit_buckets",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+				bucket_key TEXT PRIMARY KEY,
+				tokens DOUBLE PRECISION NOT NULL,
+				updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+			);`,
+			`CREATE INDEX IF NOT EXISTS rate_limit_buckets_updated_at_idx ON rate_limit_buckets (updated_at);`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS rate_limit_buckets;`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "idempotency_keys",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS idempotency_keys (
+				user_id UUID NOT NULL,
+				idempotency_key TEXT NOT NULL,
+				request_hash TEXT NOT NULL,
+				status_code INT,
+				content_type TEXT,
+				response_body BYTEA,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				completed_at TIMESTAMPTZ,
+				PRIMARY KEY (user_id, idempotency_key)
+			);`,
+			`CREATE INDEX IF NOT EXISTS idempotency_keys_created_at_idx ON idempotency_keys (created_at);`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS idempotency_keys;`,
+		},
+	},
+	{
+		Version: 4,
+		Name:    "user_roles_and_soft_delete",
+		Up: []string{
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'user'
+				CONSTRAINT users_role_check CHECK (role IN ('user', 'admin'));`,
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;`,
+			`CREATE INDEX IF NOT EXISTS users_deleted_at_idx ON users (deleted_at) WHERE deleted_at IS NOT NULL;`,
+		},
+		Down: []string{
+			`DROP INDEX IF EXISTS users_deleted_at_idx;`,
+			`ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;`,
+			`ALTER TABLE users DROP COLUMN IF EXISTS role;`,
+		},
+	},
+	{
+		Version: 5,
+		Name:    "admin_audit_log",
+		Up: []string{
+			// actor_id deliberately has no foreign key: entries must outlive
+			// the accounts they mention, including after a reset of users.
+			`CREATE TABLE IF NOT EXISTS admin_audit_log (
+				id BIGSERIAL PRIMARY KEY,
+				actor_id UUID NOT NULL,
+				action TEXT NOT NULL,
+				details JSONB NOT NULL DEFAULT '{}',
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+			);`,
+			`CREATE INDEX IF NOT EXISTS admin_audit_log_created_at_idx ON admin_audit_log (created_at);`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS admin_audit_log;`,
+		},
+	},
+	{
+		Version: 6,
+		Name:    "file_versions",
+		Up: []string{
+			`ALTER TABLE files ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;`,
+		},
+		Down: []string{
+			`ALTER TABLE files DROP COLUMN IF EXISTS version;`,
+		},
+	},
+	{
+		Version: 7,
+		Name:    "file_revisions",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS file_revisions (
+				file_id UUID NOT NULL REFERENCES files(file_uid) ON DELETE CASCADE,
+				version INT NOT NULL,
+				author_id UUID REFERENCES users(user_uid) ON DELETE SET NULL,
+				content TEXT NOT NULL,
+				content_hash CHAR(64) NOT NULL,
+				size_bytes INT NOT NULL,
+				restored_from INT,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				PRIMARY KEY (file_id, version)
+			);`,
+			// Existing files start their history at their current content.
+			`INSERT INTO file_revisions (file_id, version, content, content_hash, size_bytes, created_at)
+				SELECT file_uid, version, file_content, encode(sha256(convert_to(file_content, 'UTF8')), 'hex'),
+					octet_length(file_content), updated_at
+				FROM files
+				ON CONFLICT DO NOTHING;`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS file_revisions;`,
+		},
+	},
+	{
+		Version: 8,
+		Name:    "cascade_folder_delete",
+		Up: []string{
+			// Deleting a folder used to orphan its children to the root.
+			`ALTER TABLE folders DROP CONSTRAINT IF EXISTS folders_parent_folder_id_fkey,
+				ADD CONSTRAINT folders_parent_folder_id_fkey FOREIGN KEY (parent_folder_id) REFERENCES folders(folder_uid) ON DELETE CASCADE;`,
+			`ALTER TABLE files DROP CONSTRAINT IF EXISTS files_parent_folder_id_fkey,
+				ADD CONSTRAINT files_parent_folder_id_fkey FOREIGN KEY (parent_folder_id) REFERENCES folders(folder_uid) ON DELETE CASCADE;`,
+		},
+		Down: []string{
+			`ALTER TABLE files DROP CONSTRAINT IF EXISTS files_parent_folder_id_fkey,
+				ADD CONSTRAINT files_parent_folder_id_fkey FOREIGN KEY (parent_folder_id) REFERENCES folders(folder_uid) ON DELETE SET NULL;`,
+			`ALTER TABLE folders DROP CONSTRAINT IF EXISTS folders_parent_folder_id_fkey,
+				ADD CONSTRAINT folders_parent_folder_id_fkey FOREIGN KEY (parent_folder_id) REFERENCES folders(folder_uid) ON DELETE SET NULL;`,
+		},
+	},
+	{
+		Version: 9,
+		Name:    "unique_names_per_folder",
+		Up: []string{
+			// Earlier duplicates keep the oldest entry's name; the rest get a
+			// suffix from their ID so the indexes below can be built.
+			`UPDATE files f SET file_name = left(f.file_name, 244) || ' (' || left(f.file_uid::text, 8) || ')'
+				FROM (SELECT file_uid, ROW_NUMBER() OVER (
+						PARTITION BY project_id, parent_folder_id, file_name ORDER BY created_at, file_uid) AS n
+					FROM files) d
+				WHERE f.file_uid = d.file_uid AND d.n > 1;`,
+			`UPDATE folders f SET folder_name = left(f.folder_name, 244) || ' (' || left(f.folder_uid::text, 8) || ')'
+				FROM (SELECT folder_uid, ROW_NUMBER() OVER (
+						PARTITION BY project_id, parent_folder_id, folder_name ORDER BY created_at, folder_uid) AS n
+					FROM folders) d
+				WHERE f.folder_uid = d.folder_uid AND d.n > 1;`,
+			// NULL parents are the root, so they must compare equal.
+			`CREATE UNIQUE INDEX IF NOT EXISTS files_parent_name_key
+				ON files (project_id, COALESCE(parent_folder_id, '00000000-0000-0000-0000-000000000000'), file_name);`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS folders_parent_name_key
+				ON folders (project_id, COALESCE(parent_folder_id, '00000000-0000-0000-0000-000000000000'), folder_name);`,
+		},
+		Down: []string{
+			`DROP INDEX IF EXISTS folders_parent_name_key;`,
+			`DROP INDEX IF EXISTS files_parent_name_key;`,
+		},
+	},
+	{
+		Version: 10,
+		Name:    "parent_folder_indexes",
+		Up: []string{
+			// Listing a folder's children and cascading a folder delete both
+			// look rows up by parent.
+			`CREATE INDEX IF NOT EXISTS folders_parent_folder_id_idx ON folders (parent_folder_id);`,
+			`CREATE INDEX IF NOT EXISTS files_parent_folder_id_idx ON files (parent_folder_id);`,
+		},
+		Down: []string{
+			`DROP INDEX IF EXISTS files_parent_folder_id_idx;`,
+			`DROP INDEX IF EXISTS folders_parent_folder_id_idx;`,
+		},
+	},
+	{
+		Version: 11,
+		Name:    "file_content_trigram_index",
+		Up: []string{
+			// Code search narrows files with LIKE, ILIKE and regex matches on
+			// file_content, which a trigram index serves. Managed databases
+			// may not offer pg_trgm; search then scans the space's files
+			// without it. A tsvector index is no help here: the text search
+			// parser reads code such as obj.method as a single host name
+			// token, and it errors on content over 1 MB.
+			`DO $$
+			BEGIN
+				CREATE EXTENSION IF NOT EXISTS pg_trgm;
+			EXCEPTION WHEN insufficient_privilege OR undefined_file OR feature_not_supported THEN
+				RAISE NOTICE 'pg_trgm is not available, so file content is not indexed for search';
+			END
+			$$;`,
+			`DO $$
+			BEGIN
+				IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm') THEN
+					CREATE INDEX IF NOT EXISTS files_content_trgm_idx ON files USING gin (file_content gin_trgm_ops);
+				END IF;
+			END
+			$$;`,
+		},
+		Down: []string{
+			// The extension stays; other objects may depend on it.
+			`DROP INDEX IF EXISTS files_content_trgm_idx;`,
+		},
+	},
+	{
+		Version: 12,
+		Name:    "admin_audit_log_token_hash",
+		Up: []string{
+			// A reset records the SHA-256 of its confirmation token so the
+			// token cannot be replayed while it is still unexpired.
+			`ALTER TABLE admin_audit_log ADD COLUMN IF NOT EXISTS token_hash TEXT;`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS admin_audit_log_token_hash_key ON admin_audit_log (token_hash);`,
+		},
+		Down: []string{
+			`DROP INDEX IF EXISTS admin_audit_log_token_hash_key;`,
+			`ALTER TABLE admin_audit_log DROP COLUMN IF EXISTS token_hash;`,
+		},
+	},
+}
+
+func (pg *PostQreSQLCon) ensureMigrationsTable(ctx context.Context) error {
+	_, err := pg.dbCon.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`)
+	return err
+}
+
+func (pg *PostQreSQLCon) appliedMigrations(ctx context.Context) (map[int]time.Time, error) {
+	rows, err := pg.dbCon.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// MigrationStatus lists every known migration and when it was applied.
+func (pg *PostQreSQLCon) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	ctx, done := instrument(ctx, "MigrationStatus")
+	defer done()
+
+	if err := pg.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := pg.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if at, ok := applied[m.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// PendingMigrations returns how many known migrations are not yet applied.
+// It only reads, so /readyz can call it on every probe; a database without
+// schema_migrations has every migration pending.
+func (pg *PostQreSQLCon) PendingMigrations(ctx context.Context) (int, error) {
+	ctx, done := instrument(ctx, "PendingMigrations")
+	defer done()
+
+	var exists bool
+	err := pg.dbCon.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return len(migrations), nil
+	}
+	applied, err := pg.appliedMigrations(ctx)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; !ok {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// MigrateUp applies every pending migration in order and returns the ones it
+// applied.
+func (pg *PostQreSQLCon) MigrateUp(ctx context.Context) ([]MigrationStatus, error) {
+	ctx, done := instrument(ctx, "MigrateUp")
+	defer done()
+
+	unlock, err := pg.lockMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if err := pg.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := pg.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []MigrationStatus
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := pg.runMigration(ctx, m, m.Up, true); err != nil {
+			return ran, err
+		}
+		now := time.Now()
+		ran = append(ran, MigrationStatus{Version: m.Version, Name: m.Name, AppliedAt: &now})
+		logging.FromContext(ctx).Info("Applied migration", "version", m.Version, "name", m.Name)
+	}
+	return ran, nil
+}
+
+// MigrateDown rolls back the latest steps applied migrations and returns the
+// ones it reverted.
+func (pg *PostQreSQLCon) MigrateDown(ctx context.Context, steps int) ([]MigrationStatus, error) {
+	ctx, done := instrument(ctx, "MigrateDown")
+	defer done()
+
+	unlock, err := pg.lockMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if err := pg.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := pg.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []MigrationStatus
+	for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if err := pg.runMigration(ctx, m, m.Down, false); err != nil {
+			return reverted, err
+		}
+		reverted = append(reverted, MigrationStatus{Version: m.Version, Name: m.Name})
+		logging.FromContext(ctx).Info("Reverted migration", "version", m.Version, "name", m.Name)
+	}
+	return reverted, nil
+}
+
+func (pg *PostQreSQLCon) runMigration(ctx context.Context, m migration, statements []string, up bool) error {
+	tx, err := pg.dbCon.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+	}
+	if up {
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name)
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockMigrations takes a session-level advisory lock on a dedicated
+// connection and returns a function that releases it.
+func (pg *PostQreSQLCon) lockMigrations(ctx context.Context) (func(), error) {
+	conn, err := pg.dbCon.Connx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return func() {
+		_, _ = conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+		conn.Close()
+	}, nil
+}
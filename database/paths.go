@@ -0,0 +1,112 @@
+package database
+
+import (
+	"Hack4Change/apperrors"
+	"Hack4Change/models"
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// resolveFolderPath walks dirs from the project root and returns the ID of
+// the last folder, nil for the root itself. With create set, missing
+// folders are made on the way down; the caller then holds the project tree
+// lock.
+func resolveFolderPath(ctx context.Context, db queryer, projectID string, dirs []string, create bool) (*string, error) {
+	var parent *string
+	for _, name := range dirs {
+		var id string
+		query := `SELECT folder_uid FROM folders
+                  WHERE project_id = $1 AND parent_folder_id IS NOT DISTINCT FROM $2 AND folder_name = $3`
+		err := db.QueryRowContext(ctx, query, projectID, nullableID(parent), name).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			if !create {
+				return nil, apperrors.NotFound("folder " + name + " not found")
+			}
+			id = uuid.New().String()
+			folder := models.Folder{ID: id, ProjectID: projectID, FolderName: name, ParentFolderId: parent}
+			if err := insertFolder(ctx, db, folder); err != nil {
+				return nil, err
+			}
+		} else if err != nil {
+			return nil, mapError(err, "folder")
+		}
+		parent = &id
+	}
+	return parent, nil
+}
+
+func fetchFileByName(ctx context.Context, db queryer, projectID string, parent *string, name string) (models.File, error) {
+	var file models.File
+	query := `SELECT ` + fileColumns + ` FROM files
+              WHERE project_id = $1 AND parent_folder_id IS NOT DISTINCT FROM $2 AND file_name = $3`
+	err := scanFile(db.QueryRowContext(ctx, query, projectID, nullableID(parent), name), &file)
+	return file, mapError(err, "file")
+}
+
+// FetchFileByPath returns the file at path, given as its folder names
+// followed by the file name.
+func (pg *PostQreSQLCon) FetchFileByPath(ctx context.Context, projectID string, path []string) (models.File, error) {
+	ctx, done := instrument(ctx, "FetchFileByPath")
+	defer done()
+
+	parent, err := resolveFolderPath(ctx, pg.dbCon, projectID, path[:len(path)-1], false)
+	if err != nil {
+		return models.File{}, err
+	}
+	return fetchFileByName(ctx, pg.dbCon, projectID, parent, path[len(path)-1])
+}
+
+// WriteFileByPath creates the file at path, along with any missing folders
+// above it, or saves new content to the file already there. Overwriting
+// needs expectedVersion, as SaveFileContent does. created reports which of
+// the two happened.
+func (pg *PostQreSQLCon) WriteFileByPath(ctx context.Context, projectID string, path []string, content string, expectedVersion *int, authorID string) (file models.File, created bool, err error) {
+	ctx, done := instrument(ctx, "WriteFileByPath")
+	defer done()
+
+	tx, err := pg.dbCon.BeginTxx(ctx, nil)
+	if err != nil {
+		return file, false, mapError(err, "file")
+	}
+	defer tx.Rollback()
+
+	if err := lockProjectTree(ctx, tx, projectID); err != nil {
+		return file, false, err
+	}
+	parent, err := resolveFolderPath(ctx, tx, projectID, path[:len(path)-1], true)
+	if err != nil {
+		return file, false, err
+	}
+	name := path[len(path)-1]
+
+	existing, err := fetchFileByName(ctx, tx, projectID, parent, name)
+	switch {
+	case apperrors.Is(err, apperrors.KindNotFound):
+		if expectedVersion != nil {
+			// The client edited a file that has since gone.
+			return file, false, err
+		}
+		file = models.File{ID: uuid.New().String(), ProjectID: projectID, ParentFolderId: parent, FileName: name, FileContent: content}
+		if err := insertFile(ctx, tx, file, authorID); err != nil {
+			return file, false, err
+		}
+		if file, err = fetchFileByName(ctx, tx, projectID, parent, name); err != nil {
+			return file, false, err
+		}
+		created = true
+	case err != nil:
+		return file, false, err
+	case expectedVersion == nil:
+		return file, false, apperrors.PreconditionRequired("Overwriting a file requires the version being edited").
+			WithField("If-Match", "send the current file version, e.g. \""+strconv.Itoa(existing.Version)+"\"")
+	default:
+		if file, err = updateFileContent(ctx, tx, projectID, existing.ID, content, *expectedVersion, authorID, nil); err != nil {
+			return file, false, err
+		}
+	}
+	return file, created, mapError(tx.Commit(), "file")
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// UpdateRateLimitBucket locks the bucket for key (creating it with initial
+// tokens if needed), passes its tokens and the time since it was last
+// touched to update, and stores the returned token count. Time is measured
+// by the database clock so all instances agree. It uses clock_timestamp(),
+// not NOW(): NOW() is fixed when the transaction starts, so a request that
+// waited on the row lock would otherwise be credited too little time and
+// store a stale updated_at.
+func (pg *PostQreSQLCon) UpdateRateLimitBucket(ctx context.Context, key string, initial float64, update func(tokens float64, elapsed time.Duration) float64) error {
+	ctx, done := instrument(ctx, "UpdateRateLimitBucket")
+	defer done()
+
+	tx, err := pg.dbCon.BeginTxx(ctx, nil)
+	if err != nil {
+		return mapError(err, "rate limit bucket")
+	}
+	defer tx.Rollback()
+
+	insert := `INSERT INTO rate_limit_buckets (bucket_key, tokens, updated_at)
+               VALUES ($1, $2, clock_timestamp()) ON CONFLICT (bucket_key) DO NOTHING`
+	if _, err := tx.ExecContext(ctx, insert, key, initial); err != nil {
+		return mapError(err, "rate limit bucket")
+	}
+
+	var tokens, elapsedSeconds float64
+	query := `SELECT tokens, GREATEST(EXTRACT(EPOCH FROM (clock_timestamp() - updated_at)), 0)
+              FROM rate_limit_buckets WHERE bucket_key = $1 FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, query, key).Scan(&tokens, &elapsedSeconds); err != nil {
+		return mapError(err, "rate limit bucket")
+	}
+
+	tokens = update(tokens, time.Duration(elapsedSeconds*float64(time.Second)))
+	if _, err := tx.ExecContext(ctx, `UPDATE rate_limit_buckets SET tokens = $1, updated_at = clock_timestamp() WHERE bucket_key = $2`, tokens, key); err != nil {
+		return mapError(err, "rate limit bucket")
+	}
+	return mapError(tx.Commit(), "rate limit bucket")
+}
+
+// DeleteIdleRateLimitBuckets removes buckets untouched for longer than idle.
+func (pg *PostQreSQLCon) DeleteIdleRateLimitBuckets(ctx context.Context, idle time.Duration) error {
+	ctx, done := instrument(ctx, "DeleteIdleRateLimitBuckets")
+	defer done()
+
+	query := `DELETE FROM rate_limit_buckets WHERE updated_at < NOW() - make_interval(secs => $1)`
+	_, err := pg.dbCon.ExecContext(ctx, query, idle.Seconds())
+	return mapError(err, "rate limit bucket")
+}
@@ -0,0 +1,175 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"Hack4Change/apperrors"
+)
+
+// resetDependents lists, for every entity the admin reset may clear, the
+// tables that reference it and so must be truncated with it. Keep it in
+// step with the foreign keys in migrations: a missing entry makes TRUNCATE
+// fail rather than silently clear more than the dry run reported.
+var resetDependents = map[string][]string{
+	"users":              {"socials", "projects", "file_revisions"},
+	"socials":            nil,
+	"projects":           {"folders", "files"},
+	"folders":            {"files"},
+	"files":              {"file_revisions"},
+	"file_revisions":     nil,
+	"skills":             nil,
+	"idempotency_keys":   nil,
+	"rate_limit_buckets": nil,
+}
+
+// ResettableEntities returns the entity names accepted by ExpandResetEntities.
+func ResettableEntities() []string {
+	names := make([]string, 0, len(resetDependents))
+	for name := range resetDependents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExpandResetEntities validates the requested entities and adds every table
+// that has to be cleared along with them, returning a sorted list.
+func ExpandResetEntities(entities []string) ([]string, error) {
+	seen := map[string]bool{}
+	var visit func(name string)
+	visit = func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		for _, dep := range resetDependents[name] {
+			visit(dep)
+		}
+	}
+	for _, name := range entities {
+		if _, ok := resetDependents[name]; !ok {
+			return nil, apperrors.Validation("Unknown entity").
+				WithField("entities", fmt.Sprintf("%q is not one of %v", name, ResettableEntities()))
+		}
+		visit(name)
+	}
+
+	tables := make([]string, 0, len(seen))
+	for name := range seen {
+		tables = append(tables, name)
+	}
+	sort.Strings(tables)
+	return tables, nil
+}
+
+// CountRows returns the number of rows in each of tables, which must come
+// from ExpandResetEntities.
+func (pg *PostQreSQLCon) CountRows(ctx context.Context, tables []string) (map[string]int64, error) {
+	ctx, done := instrument(ctx, "CountRows")
+	defer done()
+
+	counts := make(map[string]int64, len(tables))
+	for _, table := range tables {
+		if _, ok := resetDependents[table]; !ok {
+			return nil, fmt.Errorf("database: %q is not a resettable table", table)
+		}
+		var n int64
+		if err := pg.dbCon.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+table).Scan(&n); err != nil {
+			return nil, mapError(err, table)
+		}
+		counts[table] = n
+	}
+	return counts, nil
+}
+
+// ResetTables truncates tables, which must come from ExpandResetEntities,
+// and records the reset in the audit log in the same transaction. It
+// returns the number of rows each table held. tokenHash identifies the
+// confirmation token that authorised the reset; a token already recorded
+// is rejected, so each one works once.
+func (pg *PostQreSQLCon) ResetTables(ctx context.Context, actorID, tokenHash string, tables []string) (map[string]int64, error) {
+	ctx, done := instrument(ctx, "ResetTables")
+	defer done()
+
+	tx, err := pg.dbCon.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, mapError(err, "reset")
+	}
+	defer tx.Rollback()
+
+	// Claim the token before touching any table. A concurrent reset with the
+	// same token waits here on the unique index and finds it taken once the
+	// first commits.
+	var auditID int64
+	claim := `INSERT INTO admin_audit_log (actor_id, action, details, token_hash, created_at)
+              VALUES ($1, 'data.reset', '{}', $2, NOW())
+              ON CONFLICT (token_hash) DO NOTHING RETURNING id`
+	err = tx.QueryRowContext(ctx, claim, actorID, tokenHash).Scan(&auditID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperrors.Validation("Confirmation token already used").
+			WithField("confirmation_token", "has already been used; run a new dry run")
+	}
+	if err != nil {
+		return nil, mapError(err, "audit log entry")
+	}
+
+	counts := make(map[string]int64, len(tables))
+	for _, table := range tables {
+		if _, ok := resetDependents[table]; !ok {
+			return nil, fmt.Errorf("database: %q is not a resettable table", table)
+		}
+		// Lock first so the counts match what TRUNCATE removes.
+		if _, err := tx.ExecContext(ctx, `LOCK TABLE `+table+` IN ACCESS EXCLUSIVE MODE`); err != nil {
+			return nil, mapError(err, table)
+		}
+		var n int64
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+table).Scan(&n); err != nil {
+			return nil, mapError(err, table)
+		}
+		counts[table] = n
+	}
+
+	if len(tables) > 0 {
+		if _, err := tx.ExecContext(ctx, `TRUNCATE TABLE `+strings.Join(tables, ", ")); err != nil {
+			return nil, mapError(err, "reset")
+		}
+	}
+	details, err := json.Marshal(map[string]any{"tables": tables, "rows": counts})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE admin_audit_log SET details = $1 WHERE id = $2`, details, auditID); err != nil {
+		return nil, mapError(err, "audit log entry")
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, mapError(err, "reset")
+	}
+	return counts, nil
+}
+
+// InsertAuditLog records an administrative action by actorID.
+func (pg *PostQreSQLCon) InsertAuditLog(ctx context.Context, actorID, action string, details any) error {
+	ctx, done := instrument(ctx, "InsertAuditLog")
+	defer done()
+	return insertAuditLog(ctx, pg.dbCon, actorID, action, details)
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func insertAuditLog(ctx context.Context, db execer, actorID, action string, details any) error {
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO admin_audit_log (actor_id, action, details, created_at) VALUES ($1, $2, $3, NOW())`
+	_, err = db.ExecContext(ctx, query, actorID, action, detailsJSON)
+	return mapError(err, "audit log entry")
+}
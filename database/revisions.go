@@ -0,0 +1,186 @@
+package database
+
+import (
+	"Hack4Change/apperrors"
+	"Hack4Change/models"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strconv"
+)
+
+const revisionColumns = `r.file_id, r.version, r.author_id, r.content_hash, r.size_bytes, r.restored_from, r.created_at`
+
+// insertRevision records content as version of fileID. authorID may be
+// empty for changes not made by a user.
+func insertRevision(ctx context.Context, db execer, fileID string, version int, authorID, content string, restoredFrom *int) error {
+	sum := sha256.Sum256([]byte(content))
+	var author any
+	if authorID != "" {
+		author = authorID
+	}
+	query := `INSERT INTO file_revisions (file_id, version, author_id, content, content_hash, size_bytes, restored_from, created_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())`
+	_, err := db.ExecContext(ctx, query, fileID, version, author, content, hex.EncodeToString(sum[:]), len(content), restoredFrom)
+	return mapError(err, "file revision")
+}
+
+// CheckFileInProject returns a not-found error unless fileID is a file of
+// projectID.
+func (pg *PostQreSQLCon) CheckFileInProject(ctx context.Context, projectID, fileID string) error {
+	ctx, done := instrument(ctx, "CheckFileInProject")
+	defer done()
+
+	var ok bool
+	query := `SELECT EXISTS (SELECT 1 FROM files WHERE file_uid = $1 AND project_id = $2)`
+	if err := pg.dbCon.QueryRowContext(ctx, query, fileID, projectID).Scan(&ok); err != nil {
+		return mapError(err, "file")
+	}
+	if !ok {
+		return apperrors.NotFound("file not found")
+	}
+	return nil
+}
+
+// ListFileRevisions returns a file's history, newest first, without content.
+func (pg *PostQreSQLCon) ListFileRevisions(ctx context.Context, projectID, fileID string) ([]models.FileRevision, error) {
+	ctx, done := instrument(ctx, "ListFileRevisions")
+	defer done()
+
+	revisions := []models.FileRevision{}
+	query := `SELECT ` + revisionColumns + `
+              FROM file_revisions r JOIN files f ON f.file_uid = r.file_id
+              WHERE r.file_id = $1 AND f.project_id = $2
+              ORDER BY r.version DESC`
+	if err := pg.dbCon.SelectContext(ctx, &revisions, query, fileID, projectID); err != nil {
+		return nil, mapError(err, "file revision")
+	}
+	if len(revisions) == 0 {
+		// Every file has at least the revision it was created with.
+		return nil, apperrors.NotFound("file not found")
+	}
+	return revisions, nil
+}
+
+// FetchFileRevision returns one revision of a file, with its content.
+func (pg *PostQreSQLCon) FetchFileRevision(ctx context.Context, projectID, fileID string, version int) (models.FileRevisionContent, error) {
+	ctx, done := instrument(ctx, "FetchFileRevision")
+	defer done()
+
+	var revision models.FileRevisionContent
+	query := `SELECT ` + revisionColumns + `, r.content
+              FROM file_revisions r JOIN files f ON f.file_uid = r.file_id
+              WHERE r.file_id = $1 AND f.project_id = $2 AND r.version = $3`
+	err := pg.dbCon.GetContext(ctx, &revision, query, fileID, projectID, version)
+	return revision, mapError(err, "file revision")
+}
+
+// ListProjectRevisions returns every revision of every file in a project,
+// with content, in the order they were saved.
+func (pg *PostQreSQLCon) ListProjectRevisions(ctx context.Context, projectID string) ([]models.ProjectRevision, error) {
+	ctx, done := instrument(ctx, "ListProjectRevisions")
+	defer done()
+
+	revisions := []models.ProjectRevision{}
+	query := `SELECT ` + revisionColumns + `, r.content,
+                     COALESCE(u.username, '') AS author_name, COALESCE(u.email, '') AS author_email
+              FROM file_revisions r
+              JOIN files f ON f.file_uid = r.file_id
+              LEFT JOIN users u ON u.user_uid = r.author_id
+              WHERE f.project_id = $1
+              ORDER BY r.created_at, r.file_id, r.version`
+	if err := pg.dbCon.SelectContext(ctx, &revisions, query, projectID); err != nil {
+		return nil, mapError(err, "file revision")
+	}
+	return revisions, nil
+}
+
+// RestoreFileRevision saves the content of an earlier revision as a new
+// version, with the same optimistic concurrency check as SaveFileContent.
+func (pg *PostQreSQLCon) RestoreFileRevision(ctx context.Context, projectID, fileID string, version, expectedVersion int, authorID string) (models.File, error) {
+	ctx, done := instrument(ctx, "RestoreFileRevision")
+	defer done()
+
+	revision, err := pg.FetchFileRevision(ctx, projectID, fileID, version)
+	if err != nil {
+		return models.File{}, err
+	}
+	return pg.saveFileContent(ctx, projectID, fileID, revision.Content, expectedVersion, authorID, &version)
+}
+
+// saveFileContent runs updateFileContent in a transaction of its own.
+func (pg *PostQreSQLCon) saveFileContent(ctx context.Context, projectID, fileID, content string, expectedVersion int, authorID string, restoredFrom *int) (models.File, error) {
+	tx, err := pg.dbCon.BeginTxx(ctx, nil)
+	if err != nil {
+		return models.File{}, mapError(err, "file")
+	}
+	defer tx.Rollback()
+
+	file, err := updateFileContent(ctx, tx, projectID, fileID, content, expectedVersion, authorID, restoredFrom)
+	if err != nil {
+		return file, err
+	}
+	return file, mapError(tx.Commit(), "file")
+}
+
+// SaveFileEdits saves every edit as a new revision in one transaction, so
+// either all files change or none do. Files are updated in ID order, so
+// concurrent calls touching the same files cannot deadlock.
+func (pg *PostQreSQLCon) SaveFileEdits(ctx context.Context, projectID string, edits []models.FileEdit, authorID string) ([]models.File, error) {
+	ctx, done := instrument(ctx, "SaveFileEdits")
+	defer done()
+
+	edits = append([]models.FileEdit(nil), edits...)
+	sort.Slice(edits, func(i, j int) bool { return edits[i].FileID < edits[j].FileID })
+
+	tx, err := pg.dbCon.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, mapError(err, "file")
+	}
+	defer tx.Rollback()
+
+	files := make([]models.File, 0, len(edits))
+	for _, edit := range edits {
+		file, err := updateFileContent(ctx, tx, projectID, edit.FileID, edit.Content, edit.ExpectedVersion, authorID, nil)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, mapError(tx.Commit(), "file")
+}
+
+// updateFileContent bumps the file's version and records the new revision.
+// When the file is no longer at expectedVersion it returns a conflict whose
+// details carry the current file.
+func updateFileContent(ctx context.Context, tx queryer, projectID, fileID, content string, expectedVersion int, authorID string, restoredFrom *int) (models.File, error) {
+	var file models.File
+	query := `UPDATE files SET file_content = $1, version = version + 1, updated_at = NOW()
+              WHERE file_uid = $2 AND project_id = $3 AND version = $4
+              RETURNING ` + fileColumns
+	err := scanFile(tx.QueryRowContext(ctx, query, content, fileID, projectID, expectedVersion), &file)
+	if errors.Is(err, sql.ErrNoRows) {
+		var current models.File
+		query := `SELECT ` + fileColumns + ` FROM files WHERE file_uid = $1 AND project_id = $2`
+		if err := scanFile(tx.QueryRowContext(ctx, query, fileID, projectID), &current); err != nil {
+			return file, mapError(err, "file")
+		}
+		return file, apperrors.Conflict("File was saved by someone else since version "+strconv.Itoa(expectedVersion)).
+			WithField("expected_version", "current version is "+strconv.Itoa(current.Version)).
+			WithDetails(map[string]any{"current": current})
+	}
+	if err != nil {
+		return file, mapError(err, "file")
+	}
+
+	if err := insertRevision(ctx, tx, file.ID, file.Version, authorID, content, restoredFrom); err != nil {
+		return file, err
+	}
+	if err := touchAncestors(ctx, tx, projectID, file.ParentFolderId); err != nil {
+		return file, err
+	}
+	return file, nil
+}
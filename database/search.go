@@ -0,0 +1,81 @@
+package database
+
+import (
+	"Hack4Change/models"
+	"context"
+	"path"
+	"sort"
+	"strings"
+)
+
+// likeEscaper escapes the LIKE wildcards, with backslash as the default
+// escape character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ListSearchFiles returns the project's files that contain literal, with
+// their paths, in path order. The comparison ignores case when foldCase is
+// set, and an empty literal returns every file. The trigram index on
+// file_content serves both LIKE and ILIKE; without it the project's files
+// are scanned.
+func (pg *PostQreSQLCon) ListSearchFiles(ctx context.Context, projectID, literal string, foldCase bool) ([]models.PathFile, error) {
+	ctx, done := instrument(ctx, "ListSearchFiles")
+	defer done()
+
+	folders, err := pg.loadFolders(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + fileColumns + ` FROM files WHERE project_id = $1`
+	args := []any{projectID}
+	if literal != "" {
+		op := "LIKE"
+		if foldCase {
+			op = "ILIKE"
+		}
+		query += ` AND file_content ` + op + ` $2`
+		args = append(args, "%"+likeEscaper.Replace(literal)+"%")
+	}
+	rows, err := pg.dbCon.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, mapError(err, "file")
+	}
+	defer rows.Close()
+
+	dirs := folderPaths(folders)
+	files := []models.PathFile{}
+	for rows.Next() {
+		var file models.PathFile
+		if err := rows.Scan(&file.ID, &file.ProjectID, &file.ParentFolderId, &file.FileName, &file.FileContent, &file.Version, &file.CreatedAt, &file.UpdatedAt); err != nil {
+			return nil, mapError(err, "file")
+		}
+		file.Path = file.FileName
+		if file.ParentFolderId != nil {
+			dir, ok := dirs[*file.ParentFolderId]
+			if !ok {
+				continue
+			}
+			file.Path = path.Join(dir, file.FileName)
+		}
+		files = append(files, file)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, mapError(err, "file")
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// folderPaths maps folder IDs to paths. folders must list parents before
+// children, as loadFolders does.
+func folderPaths(folders []models.Folder) map[string]string {
+	paths := make(map[string]string, len(folders))
+	for _, folder := range folders {
+		if folder.ParentFolderId == nil {
+			paths[folder.ID] = folder.FolderName
+		} else {
+			paths[folder.ID] = path.Join(paths[*folder.ParentFolderId], folder.FolderName)
+		}
+	}
+	return paths
+}
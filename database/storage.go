@@ -2,8 +2,11 @@ package database
 
 import (
 	"Hack4Change/models"
+	"context"
+	"database/sql"
 	"encoding/json"
-	"log/slog"
+	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
@@ -14,176 +17,10 @@ type PostQreSQLCon struct {
 	dbCon *sqlx.DB
 }
 
-func (pg *PostQreSQLCon) CreateTablesX() error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS users (
-			user_uid UUID PRIMARY KEY,
-			username VARCHAR(32) NOT NULL UNIQUE,
-			email VARCHAR(255) NOT NULL UNIQUE,
-			phone VARCHAR(20),
-			first_name VARCHAR(32),
-			last_name VARCHAR(32),
-			password_hash TEXT NOT NULL,
-			social_accounts JSONB,
-			badges JSONB,
-			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
-		);`,
-		`CREATE TABLE IF NOT EXISTS socials (
-			socials_uid UUID PRIMARY KEY,
-			user_id UUID REFERENCES users(user_uid) ON DELETE CASCADE,
-			github VARCHAR(255),
-			linkedin VARCHAR(255),
-			instagram VARCHAR(255),
-			noobs_social VARCHAR(255)
-		);`,
-		`CREATE TABLE IF NOT EXISTS projects (
-			project_uid UUID PRIMARY KEY,
-			user_id UUID REFERENCES users(user_uid) ON DELETE CASCADE,
-			project_name VARCHAR(50) NOT NULL,
-			project_description VARCHAR(255),
-			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
-		);`,
-		`CREATE TABLE IF NOT EXISTS files (
-			file_uid UUID PRIMARY KEY,
-			project_id UUID REFERENCES projects(project_uid) ON DELETE CASCADE,
-			file_name VARCHAR(255) NOT NULL,
-			file_content TEXT NOT NULL,
-			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
-		);`,
-		`CREATE TABLE IF NOT EXISTS folders (
-			folder_uid UUID PRIMARY KEY,
-			project_id UUID REFERENCES projects(project_uid) ON DELETE CASCADE,
-			folder_name VARCHAR(255) NOT NULL,
-			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
-		);`,
-		`CREATE TABLE IF NOT EXISTS skills (
-			skill_uid UUID PRIMARY KEY,
-			topic VARCHAR(255) NOT NULL,
-			intro TEXT NOT NULL,
-			data JSONB NOT NULL,
-			user_ids TEXT[] NOT NULL
-		);`,
-	}
-
-	for _, query := range queries {
-		if _, err := pg.dbCon.Exec(query); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func (pg *PostQreSQLCon) CreateTables() error {
-	if err := pg.CreateUsersTable(); err != nil {
-		return err
-	}
-	if err := pg.CreateSocialsTable(); err != nil {
-		return err
-	}
-	if err := pg.CreateProjectsTable(); err != nil {
-		return err
-	}
-	if err := pg.CreateFoldersTable(); err != nil {
-		return err
-	}
-	if err := pg.CreateFilesTable(); err != nil {
-		return err
-	}
-
-	if err := pg.CreateSkillsTable(); err != nil {
-		return err
-	}
-	return nil
-}
-
-func (pg *PostQreSQLCon) CreateUsersTable() error {
-	query := `CREATE TABLE IF NOT EXISTS users (
-		user_uid UUID PRIMARY KEY,
-		username VARCHAR(32) NOT NULL UNIQUE,
-		email VARCHAR(255) NOT NULL UNIQUE,
-		phone VARCHAR(20),
-		first_name VARCHAR(32),
-		last_name VARCHAR(32),
-		password_hash TEXT NOT NULL,
-		social_accounts JSONB,
-		badges JSONB,
-		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
-	);`
-	_, err := pg.dbCon.Exec(query)
-	return err
-}
-
-func (pg *PostQreSQLCon) CreateSocialsTable() error {
-	query := `CREATE TABLE IF NOT EXISTS socials (
-		socials_uid UUID PRIMARY KEY,
-		user_id UUID REFERENCES users(user_uid) ON DELETE CASCADE,
-		github VARCHAR(255),
-		linkedin VARCHAR(255),
-		instagram VARCHAR(255),
-		noobs_social VARCHAR(255)
-	);`
-	_, err := pg.dbCon.Exec(query)
-	return err
-}
-
-func (pg *PostQreSQLCon) CreateProjectsTable() error {
-	query := `CREATE TABLE IF NOT EXISTS projects (
-		project_uid UUID PRIMARY KEY,
-		user_id UUID REFERENCES users(user_uid) ON DELETE CASCADE,
-		project_name VARCHAR(50) NOT NULL,
-		project_description VARCHAR(255),
-		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
-	);`
-	_, err := pg.dbCon.Exec(query)
-	return err
-}
-
-func (pg *PostQreSQLCon) CreateFilesTable() error {
-	query := `CREATE TABLE IF NOT EXISTS files (
-		file_uid UUID PRIMARY KEY,
-		project_id UUID REFERENCES projects(project_uid) ON DELETE CASCADE,
-		file_name VARCHAR(255) NOT NULL,
-		file_content TEXT NOT NULL,
-		parent_folder_id UUID REFERENCES folders(folder_uid) ON DELETE SET NULL,
-		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
-	);`
-	_, err := pg.dbCon.Exec(query)
-	return err
-}
-
-func (pg *PostQreSQLCon) CreateFoldersTable() error {
-	query := `CREATE TABLE IF NOT EXISTS folders (
-		folder_uid UUID PRIMARY KEY,
-		project_id UUID REFERENCES projects(project_uid) ON DELETE CASCADE,
-		folder_name VARCHAR(255) NOT NULL,
-		parent_folder_id UUID REFERENCES folders(folder_uid) ON DELETE SET NULL,
-		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
-	);`
-	_, err := pg.dbCon.Exec(query)
-	return err
-}
-
-func (pg *PostQreSQLCon) CreateSkillsTable() error {
-	query := `CREATE TABLE IF NOT EXISTS skills (
-		skill_uid UUID PRIMARY KEY,
-		topic VARCHAR(255) NOT NULL,
-		intro TEXT NOT NULL,
-		data JSONB NOT NULL,
-		user_ids TEXT[] NOT NULL
-	);`
-	_, err := pg.dbCon.Exec(query)
-	return err
-}
-
-func (pg *PostQreSQLCon) InsertUser(user models.UserDetails, passwordHash string) error {
+// InsertUser creates an account. An empty user.Role means models.RoleUser.
+func (pg *PostQreSQLCon) InsertUser(ctx context.Context, user models.UserDetails, passwordHash string) error {
+	ctx, done := instrument(ctx, "InsertUser")
+	defer done()
 	// Initialize dummy values for social_accounts and badges
 	emptySocials := models.Socials{}
 	emptyBadges := []models.Badge{}
@@ -199,86 +36,128 @@ func (pg *PostQreSQLCon) InsertUser(user models.UserDetails, passwordHash string
 		return err
 	}
 
-	query := `INSERT INTO users (user_uid, username, email, phone, first_name, last_name, password_hash, social_accounts, badges, created_at, updated_at)
-              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())`
-	_, err = pg.dbCon.Exec(query, user.ID, user.Username, user.Email, user.Phone, user.FirstName, user.LastName, passwordHash, socialAccountsJSON, badgesJSON)
-	return err
+	role := user.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+
+	query := `INSERT INTO users (user_uid, username, email, phone, first_name, last_name, password_hash, social_accounts, badges, role, created_at, updated_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())`
+	_, err = pg.dbCon.ExecContext(ctx, query, user.ID, user.Username, user.Email, user.Phone, user.FirstName, user.LastName, passwordHash, socialAccountsJSON, badgesJSON, role)
+	return mapError(err, "user")
 }
 
-func (pg *PostQreSQLCon) InsertSocialAccounts(userID string, socials models.Socials) error {
+func (pg *PostQreSQLCon) InsertSocialAccounts(ctx context.Context, userID string, socials models.Socials) error {
+	ctx, done := instrument(ctx, "InsertSocialAccounts")
+	defer done()
 	query := `INSERT INTO socials (socials_uid, user_id, github, linkedin, instagram, noobs_social)
               VALUES ($1, $2, $3, $4, $5)`
-	_, err := pg.dbCon.Exec(query, uuid.New().String(), userID, socials.GitHub, socials.LinkedIn, socials.Instagram, socials.NoobsSocial)
-	return err
+	_, err := pg.dbCon.ExecContext(ctx, query, uuid.New().String(), userID, socials.GitHub, socials.LinkedIn, socials.Instagram, socials.NoobsSocial)
+	return mapError(err, "socials")
 }
 
-func (pg *PostQreSQLCon) InsertProject(project models.ProjectDetails) error {
+func (pg *PostQreSQLCon) InsertProject(ctx context.Context, project models.ProjectDetails) error {
+	ctx, done := instrument(ctx, "InsertProject")
+	defer done()
 	query := `INSERT INTO projects (project_uid, user_id, project_name, project_description, created_at, updated_at)
               VALUES ($1, $2, $3, $4, NOW(), NOW())`
-	_, err := pg.dbCon.Exec(query, project.ProjectID, project.OwnerID, project.ProjectName, project.ProjectDescription)
-	return err
+	_, err := pg.dbCon.ExecContext(ctx, query, project.ProjectID, project.OwnerID, project.ProjectName, project.ProjectDescription)
+	return mapError(err, "project")
 }
-func (pg *PostQreSQLCon) InsertFile(file models.File) error {
-	var parentFolderId interface{}
-	if file.ParentFolderId == nil || *file.ParentFolderId == "" {
-		parentFolderId = nil
-	} else {
-		parentFolderId = *file.ParentFolderId
-	}
-
-	query := `INSERT INTO files (file_uid, project_id, parent_folder_id, file_name, file_content, created_at, updated_at)
-              VALUES ($1, $2, $3, $4, $5, NOW(), NOW())`
+func (pg *PostQreSQLCon) InsertFile(ctx context.Context, file models.File, authorID string) error {
+	ctx, done := instrument(ctx, "InsertFile")
+	defer done()
 
-	_, err := pg.dbCon.Exec(query, file.ID, file.ProjectID, parentFolderId, file.FileName, file.FileContent)
+	tx, err := pg.dbCon.BeginTxx(ctx, nil)
 	if err != nil {
-		slog.Error("InsertFile: Error inserting file", "fileID", file.ID, "error", err)
+		return mapError(err, "file")
 	}
-	return err
-}
+	defer tx.Rollback()
 
-func (pg *PostQreSQLCon) InsertFolder(folder models.Folder) error {
-	var parentFolderId interface{}
-	if folder.ParentFolderId == nil || *folder.ParentFolderId == "" {
-		parentFolderId = nil
-	} else {
-		parentFolderId = *folder.ParentFolderId
+	if err := lockProjectTree(ctx, tx, file.ProjectID); err != nil {
+		return err
+	}
+	if err := insertFile(ctx, tx, file, authorID); err != nil {
+		return err
 	}
+	return mapError(tx.Commit(), "file")
+}
 
-	query := `INSERT INTO folders (folder_uid, project_id, folder_name, parent_folder_id, created_at, updated_at)
-              VALUES ($1, $2, $3, $4, NOW(), NOW())`
+func (pg *PostQreSQLCon) InsertFolder(ctx context.Context, folder models.Folder) error {
+	ctx, done := instrument(ctx, "InsertFolder")
+	defer done()
 
-	_, err := pg.dbCon.Exec(query, folder.ID, folder.ProjectID, folder.FolderName, parentFolderId)
+	tx, err := pg.dbCon.BeginTxx(ctx, nil)
 	if err != nil {
-		slog.Error("InsertFolder: Error inserting folder", "folderID", folder.ID, "error", err)
+		return mapError(err, "folder")
 	}
-	return err
+	defer tx.Rollback()
+
+	if err := lockProjectTree(ctx, tx, folder.ProjectID); err != nil {
+		return err
+	}
+	if err := insertFolder(ctx, tx, folder); err != nil {
+		return err
+	}
+	return mapError(tx.Commit(), "folder")
 }
 
-func (con *PostQreSQLCon) FetchHashedPassword(email string) (string, error) {
+func (con *PostQreSQLCon) FetchHashedPassword(ctx context.Context, email string) (string, error) {
+	ctx, done := instrument(ctx, "FetchHashedPassword")
+	defer done()
 	var hashedPassword string
-	query := `SELECT password_hash FROM users WHERE email = $1`
-	err := con.dbCon.QueryRow(query, email).Scan(&hashedPassword)
+	query := `SELECT password_hash FROM users WHERE email = $1 AND deleted_at IS NULL`
+	err := con.dbCon.QueryRowContext(ctx, query, email).Scan(&hashedPassword)
 	if err != nil {
-		return "", err
+		return "", mapError(err, "user")
 	}
 	return hashedPassword, nil
 }
 
-func (con *PostQreSQLCon) FetchUserIdByEmail(email string) (string, error) {
+func (con *PostQreSQLCon) FetchUserIdByEmail(ctx context.Context, email string) (string, error) {
+	ctx, done := instrument(ctx, "FetchUserIdByEmail")
+	defer done()
 	var userID string
-	query := `SELECT user_uid FROM users WHERE email = $1;`
-	err := con.dbCon.QueryRow(query, email).Scan(&userID)
+	query := `SELECT user_uid FROM users WHERE email = $1 AND deleted_at IS NULL;`
+	err := con.dbCon.QueryRowContext(ctx, query, email).Scan(&userID)
 	if err != nil {
-		return "", err
+		return "", mapError(err, "user")
 	}
 	return userID, nil
 }
 
-func (con *PostQreSQLCon) FetchProjectsByUserId(userId string) ([]models.ProjectDetails, error) {
+// FetchProject returns one project.
+func (con *PostQreSQLCon) FetchProject(ctx context.Context, projectID string) (models.ProjectDetails, error) {
+	ctx, done := instrument(ctx, "FetchProject")
+	defer done()
+	var project models.ProjectDetails
+	query := `SELECT project_uid, user_id, project_name, COALESCE(project_description, ''), created_at, updated_at
+              FROM projects WHERE project_uid = $1`
+	err := con.dbCon.QueryRowContext(ctx, query, projectID).Scan(
+		&project.ProjectID, &project.OwnerID, &project.ProjectName, &project.ProjectDescription, &project.CreatedAt, &project.UpdatedAt)
+	return project, mapError(err, "project")
+}
+
+// ProjectUpdatedAt returns when anything in the project last changed, or
+// the zero time when there is no such project.
+func (con *PostQreSQLCon) ProjectUpdatedAt(ctx context.Context, projectID string) (time.Time, error) {
+	ctx, done := instrument(ctx, "ProjectUpdatedAt")
+	defer done()
+	var updatedAt time.Time
+	err := con.dbCon.QueryRowContext(ctx, `SELECT updated_at FROM projects WHERE project_uid = $1`, projectID).Scan(&updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return updatedAt, mapError(err, "project")
+}
+
+func (con *PostQreSQLCon) FetchProjectsByUserId(ctx context.Context, userId string) ([]models.ProjectDetails, error) {
+	ctx, done := instrument(ctx, "FetchProjectsByUserId")
+	defer done()
 	query := `SELECT project_uid, user_id, project_name, project_description FROM projects WHERE user_id = $1`
-	rows, err := con.dbCon.Queryx(query, userId)
+	rows, err := con.dbCon.QueryxContext(ctx, query, userId)
 	if err != nil {
-		return nil, err
+		return nil, mapError(err, "project")
 	}
 	defer rows.Close()
 
@@ -287,68 +166,87 @@ func (con *PostQreSQLCon) FetchProjectsByUserId(userId string) ([]models.Project
 		var project models.ProjectDetails
 		err := rows.Scan(&project.ProjectID, &project.OwnerID, &project.ProjectName, &project.ProjectDescription)
 		if err != nil {
-			return nil, err
+			return nil, mapError(err, "project")
 		}
 		projects = append(projects, project)
 	}
-	return projects, nil
+	return projects, mapError(rows.Err(), "project")
 }
 
-func (con *PostQreSQLCon) FetchFilesByProjectId(projectId string) ([]models.File, error) {
-	query := `SELECT file_uid, project_id, parent_folder_id, file_name, file_content, created_at, updated_at 
+func (con *PostQreSQLCon) FetchFilesByProjectId(ctx context.Context, projectId string) ([]models.File, error) {
+	ctx, done := instrument(ctx, "FetchFilesByProjectId")
+	defer done()
+	query := `SELECT file_uid, project_id, parent_folder_id, file_name, file_content, version, created_at, updated_at 
               FROM files WHERE project_id = $1`
-	rows, err := con.dbCon.Queryx(query, projectId)
+	rows, err := con.dbCon.QueryxContext(ctx, query, projectId)
 	if err != nil {
-		return nil, err
+		return nil, mapError(err, "file")
 	}
 	defer rows.Close()
 
 	var files []models.File
 	for rows.Next() {
 		var file models.File
-		err := rows.Scan(&file.ID, &file.ProjectID, &file.ParentFolderId, &file.FileName, &file.FileContent, &file.CreatedAt, &file.UpdatedAt)
+		err := rows.Scan(&file.ID, &file.ProjectID, &file.ParentFolderId, &file.FileName, &file.FileContent, &file.Version, &file.CreatedAt, &file.UpdatedAt)
 		if err != nil {
-			return nil, err
+			return nil, mapError(err, "file")
 		}
 		files = append(files, file)
 	}
 
-	return files, nil
+	return files, mapError(rows.Err(), "file")
 }
 
-func (con *PostQreSQLCon) FetchFoldersByProjectId(projectId string) ([]models.FolderDetails, error) {
+func (con *PostQreSQLCon) FetchFoldersByProjectId(ctx context.Context, projectId string) ([]models.FolderDetails, error) {
+	ctx, done := instrument(ctx, "FetchFoldersByProjectId")
+	defer done()
 	query := `SELECT folder_uid, project_id, folder_name, created_at, updated_at FROM folders WHERE project_id =$1;`
-	rows, err := con.dbCon.Query(query, projectId)
+	rows, err := con.dbCon.QueryContext(ctx, query, projectId)
 	if err != nil {
-		return nil, err
+		return nil, mapError(err, "folder")
 	}
 	defer rows.Close()
 	var folders []models.FolderDetails
 	for rows.Next() {
 		var folder models.FolderDetails
 		if err := rows.Scan(&folder.ID, &folder.ProjectID, &folder.FolderName, &folder.CreatedAt, &folder.UpdatedAt); err != nil {
-			return nil, err
+			return nil, mapError(err, "folder")
 		}
 		folders = append(folders, folder)
 	}
-	return folders, nil
+	return folders, mapError(rows.Err(), "folder")
 }
 
-func (con *PostQreSQLCon) SaveContent(content string) error {
-	var req models.SaveFileRequest
-	query := `UPDATE files SET file_content = $1, updated_at = NOW() WHERE id = $2 AND project_id = $3;`
-	if _, err := con.dbCon.Exec(query, req.Content, req.FileID, req.ProjectID); err != nil {
+// SaveFileContent replaces a file's content if its version is still
+// expectedVersion, bumping the version and recording a revision by
+// authorID. When someone else saved first it returns a conflict whose
+// details carry the current file.
+func (con *PostQreSQLCon) SaveFileContent(ctx context.Context, projectID, fileID, content string, expectedVersion int, authorID string) (models.File, error) {
+	ctx, done := instrument(ctx, "SaveFileContent")
+	defer done()
+	return con.saveFileContent(ctx, projectID, fileID, content, expectedVersion, authorID, nil)
+}
 
-		return err
-	}
-	return nil
+// FetchFile returns one file of a project.
+func (con *PostQreSQLCon) FetchFile(ctx context.Context, projectID, fileID string) (models.File, error) {
+	ctx, done := instrument(ctx, "FetchFile")
+	defer done()
+
+	var file models.File
+	query := `SELECT file_uid, project_id, parent_folder_id, file_name, file_content, version, created_at, updated_at
+              FROM files WHERE file_uid = $1 AND project_id = $2`
+	err := con.dbCon.QueryRowContext(ctx, query, fileID, projectID).Scan(
+		&file.ID, &file.ProjectID, &file.ParentFolderId, &file.FileName, &file.FileContent, &file.Version, &file.CreatedAt, &file.UpdatedAt)
+	return file, mapError(err, "file")
 }
-func (con *PostQreSQLCon) FetchUserDetails(userId string) (*models.UserDetails, error) {
-	query := `SELECT user_uid, username, email, phone, first_name, last_name, social_accounts, badges, created_at, updated_at FROM users WHERE user_uid=$1`
+func (con *PostQreSQLCon) FetchUserDetails(ctx context.Context, userId string) (*models.UserDetails, error) {
+	ctx, done := instrument(ctx, "FetchUserDetails")
+	defer done()
+	query := `SELECT user_uid, username, email, phone, first_name, last_name, social_accounts, badges, role, created_at, updated_at FROM users WHERE user_uid=$1 AND deleted_at IS NULL`
 	var user models.UserDetails
 	var socialAccountsJSON, badgesJSON []byte
 
-	err := con.dbCon.QueryRow(query, userId).Scan(
+	err := con.dbCon.QueryRowContext(ctx, query, userId).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
@@ -357,11 +255,12 @@ func (con *PostQreSQLCon) FetchUserDetails(userId string) (*models.UserDetails,
 		&user.LastName,
 		&socialAccountsJSON,
 		&badgesJSON,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
 	if err != nil {
-		return nil, err
+		return nil, mapError(err, "user")
 	}
 
 	if err := json.Unmarshal(socialAccountsJSON, &user.SocialAccounts); err != nil {
@@ -374,7 +273,9 @@ func (con *PostQreSQLCon) FetchUserDetails(userId string) (*models.UserDetails,
 	return &user, nil
 }
 
-func (con *PostQreSQLCon) AddSkill(skill *models.Skill) error {
+func (con *PostQreSQLCon) AddSkill(ctx context.Context, skill *models.Skill) error {
+	ctx, done := instrument(ctx, "AddSkill")
+	defer done()
 	query := `INSERT INTO skills (skill_uid, topic, intro, data, user_ids) VALUES ($1, $2, $3, $4, $5);`
 
 	id := uuid.New().String()
@@ -383,17 +284,19 @@ func (con *PostQreSQLCon) AddSkill(skill *models.Skill) error {
 		return err
 	}
 
-	_, err = con.dbCon.Exec(query, id, skill.Topic, skill.Intro, dataJSON, pq.Array(skill.UserIds))
+	_, err = con.dbCon.ExecContext(ctx, query, id, skill.Topic, skill.Intro, dataJSON, pq.Array(skill.UserIds))
 	if err != nil {
-		return err
+		return mapError(err, "skill")
 	}
 	return nil
 }
-func (con *PostQreSQLCon) FetchSkillIdAndNameByUserID(userID string) ([]models.SkillDetails, error) {
+func (con *PostQreSQLCon) FetchSkillIdAndNameByUserID(ctx context.Context, userID string) ([]models.SkillDetails, error) {
+	ctx, done := instrument(ctx, "FetchSkillIdAndNameByUserID")
+	defer done()
 	query := `SELECT skill_uid, topic FROM skills WHERE $1 = ANY(user_ids);`
-	rows, err := con.dbCon.Query(query, userID)
+	rows, err := con.dbCon.QueryContext(ctx, query, userID)
 	if err != nil {
-		return nil, err
+		return nil, mapError(err, "skill")
 	}
 	defer rows.Close()
 
@@ -401,26 +304,28 @@ func (con *PostQreSQLCon) FetchSkillIdAndNameByUserID(userID string) ([]models.S
 	for rows.Next() {
 		var skill models.SkillDetails
 		if err := rows.Scan(&skill.SkillId, &skill.Topic); err != nil {
-			return nil, err
+			return nil, mapError(err, "skill")
 		}
 		skills = append(skills, skill)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, mapError(err, "skill")
 	}
 
 	return skills, nil
 }
 
-func (con *PostQreSQLCon) FetchSkillsBySkillID(skillID string) (*models.SkillDetails, error) {
+func (con *PostQreSQLCon) FetchSkillsBySkillID(ctx context.Context, skillID string) (*models.SkillDetails, error) {
+	ctx, done := instrument(ctx, "FetchSkillsBySkillID")
+	defer done()
 	query := `SELECT skill_uid, topic, intro, data, user_ids FROM skills WHERE id=$1;`
 	var skill models.SkillDetails
 	var dataJSON []byte
 
-	err := con.dbCon.QueryRow(query, skillID).Scan(&skill.SkillId, &skill.Topic, &skill.Intro, &dataJSON, pq.Array(&skill.UserIds))
+	err := con.dbCon.QueryRowContext(ctx, query, skillID).Scan(&skill.SkillId, &skill.Topic, &skill.Intro, &dataJSON, pq.Array(&skill.UserIds))
 	if err != nil {
-		return nil, err
+		return nil, mapError(err, "skill")
 	}
 
 	if err := json.Unmarshal(dataJSON, &skill.Data); err != nil {
@@ -429,193 +334,21 @@ func (con *PostQreSQLCon) FetchSkillsBySkillID(skillID string) (*models.SkillDet
 
 	return &skill, nil
 }
-func (con *PostQreSQLCon) SubmitSolutionByQIDandSkillID(qid string, skillid string) error {
-	return nil
-}
-
-// delete tables
-func (pg *PostQreSQLCon) DropUsersTable() error {
-	query := `DROP TABLE IF EXISTS users;`
-	_, err := pg.dbCon.Exec(query)
-	return err
-}
-
-// DropSocialsTable drops the socials table
-func (pg *PostQreSQLCon) DropSocialsTable() error {
-	query := `DROP TABLE IF EXISTS socials;`
-	_, err := pg.dbCon.Exec(query)
-	return err
-}
-
-// DropProjectsTable drops the projects table
-func (pg *PostQreSQLCon) DropProjectsTable() error {
-	query := `DROP TABLE IF EXISTS projects;`
-	_, err := pg.dbCon.Exec(query)
-	return err
-}
-
-// DropFilesTable drops the files table
-func (pg *PostQreSQLCon) DropFilesTable() error {
-	query := `DROP TABLE IF EXISTS files;`
-	_, err := pg.dbCon.Exec(query)
-	return err
-}
-
-// DropFoldersTable drops the folders table
-func (pg *PostQreSQLCon) DropFoldersTable() error {
-	query := `DROP TABLE IF EXISTS folders;`
-	_, err := pg.dbCon.Exec(query)
-	return err
-}
-
-// DropSkillsTable drops the skills table
-func (pg *PostQreSQLCon) DropSkillsTable() error {
-	query := `DROP TABLE IF EXISTS skills;`
-	_, err := pg.dbCon.Exec(query)
-	return err
-}
-
-func (pg *PostQreSQLCon) DropAllTables() error {
-	// Drop tables in order to satisfy foreign key constraints
-	tables := []string{"files", "folders", "projects", "socials", "skills", "users"}
-	for _, table := range tables {
-		query := `DROP TABLE IF EXISTS ` + table + ` CASCADE;`
-		_, err := pg.dbCon.Exec(query)
-		if err != nil {
-			return err
-		}
-	}
+func (con *PostQreSQLCon) SubmitSolutionByQIDandSkillID(ctx context.Context, qid string, skillid string) error {
+	ctx, done := instrument(ctx, "SubmitSolutionByQIDandSkillID")
+	defer done()
 	return nil
 }
 
-func (con *PostQreSQLCon) UpdateSocialAccounts(userId string, socials models.Socials) error {
+func (con *PostQreSQLCon) UpdateSocialAccounts(ctx context.Context, userId string, socials models.Socials) error {
+	ctx, done := instrument(ctx, "UpdateSocialAccounts")
+	defer done()
 	socialAccountsJSON, err := json.Marshal(socials)
 	if err != nil {
 		return err
 	}
 
 	query := `UPDATE users SET social_accounts = $1, updated_at = NOW() WHERE user_uid = $2`
-	_, err = con.dbCon.Exec(query, socialAccountsJSON, userId)
-	return err
-}
-func (pg *PostQreSQLCon) GetFoldersWithContents(projectID string) ([]models.FolderDetails, error) {
-	query := `
-        SELECT folder_uid, project_id, folder_name, parent_folder_id, created_at, updated_at
-        FROM folders
-        WHERE project_id = $1
-    `
-	rows, err := pg.dbCon.Query(query, projectID)
-	if err != nil {
-		slog.Error("GetFoldersWithContents: Error querying folders", "projectID", projectID, "error", err)
-		return nil, err
-	}
-	defer rows.Close()
-
-	var folders []models.FolderDetails
-	for rows.Next() {
-		var folder models.FolderDetails
-		if err := rows.Scan(&folder.ID, &folder.ProjectID, &folder.FolderName, &folder.ParentFolderId, &folder.CreatedAt, &folder.UpdatedAt); err != nil {
-			slog.Error("GetFoldersWithContents: Error scanning folder", "projectID", projectID, "error", err)
-			return nil, err
-		}
-
-		files, err := pg.GetFilesInFolder(folder.ID)
-		if err != nil {
-			slog.Error("GetFoldersWithContents: Error fetching files in folder", "folderID", folder.ID, "error", err)
-			return nil, err
-		}
-		folder.Files = files
-		folders = append(folders, folder)
-	}
-
-	if err = rows.Err(); err != nil {
-		slog.Error("GetFoldersWithContents: Error with rows", "projectID", projectID, "error", err)
-		return nil, err
-	}
-
-	return folders, nil
-}
-
-func (pg *PostQreSQLCon) GetFilesInFolder(folderID string) ([]models.File, error) {
-	query := `
-        SELECT file_uid, project_id, parent_folder_id, file_name, file_content, created_at, updated_at
-        FROM files
-        WHERE parent_folder_id = $1
-    `
-	rows, err := pg.dbCon.Query(query, folderID)
-	if err != nil {
-		slog.Error("GetFilesInFolder: Error querying files", "folderID", folderID, "error", err)
-		return nil, err
-	}
-	defer rows.Close()
-
-	var files []models.File
-	for rows.Next() {
-		var file models.File
-		if err := rows.Scan(&file.ID, &file.ProjectID, &file.ParentFolderId, &file.FileName, &file.FileContent, &file.CreatedAt, &file.UpdatedAt); err != nil {
-			slog.Error("GetFilesInFolder: Error scanning file", "folderID", folderID, "error", err)
-			return nil, err
-		}
-		files = append(files, file)
-	}
-
-	if err = rows.Err(); err != nil {
-		slog.Error("GetFilesInFolder: Error with rows", "folderID", folderID, "error", err)
-		return nil, err
-	}
-
-	return files, nil
-}
-
-func (pg *PostQreSQLCon) GetRootFiles(projectID string) ([]models.File, error) {
-	query := `
-        SELECT file_uid, project_id, parent_folder_id, file_name, file_content, created_at, updated_at
-        FROM files
-        WHERE project_id = $1 AND parent_folder_id IS NULL
-    `
-	rows, err := pg.dbCon.Query(query, projectID)
-	if err != nil {
-		slog.Error("GetRootFiles: Error querying root files", "projectID", projectID, "error", err)
-		return nil, err
-	}
-	defer rows.Close()
-
-	var files []models.File
-	for rows.Next() {
-		var file models.File
-		if err := rows.Scan(&file.ID, &file.ProjectID, &file.ParentFolderId, &file.FileName, &file.FileContent, &file.CreatedAt, &file.UpdatedAt); err != nil {
-			slog.Error("GetRootFiles: Error scanning file", "projectID", projectID, "error", err)
-			return nil, err
-		}
-		files = append(files, file)
-	}
-
-	if err = rows.Err(); err != nil {
-		slog.Error("GetRootFiles: Error with rows", "projectID", projectID, "error", err)
-		return nil, err
-	}
-
-	return files, nil
-}
-
-func (pg *PostQreSQLCon) GetProjectStructure(projectID string) (models.ProjectContents, error) {
-	var projectContents models.ProjectContents
-
-	// Fetch folders and their contents
-	folders, err := pg.GetFoldersWithContents(projectID)
-	if err != nil {
-		slog.Error("GetProjectStructure: Error fetching folders with contents", "projectID", projectID, "error", err)
-		return projectContents, err
-	}
-	projectContents.Folders = folders
-
-	// Fetch root-level files
-	rootFiles, err := pg.GetRootFiles(projectID)
-	if err != nil {
-		slog.Error("GetProjectStructure: Error fetching root files", "projectID", projectID, "error", err)
-		return projectContents, err
-	}
-	projectContents.Files = rootFiles
-
-	return projectContents, nil
+	_, err = con.dbCon.ExecContext(ctx, query, socialAccountsJSON, userId)
+	return mapError(err, "user")
 }
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"Hack4Change/logging"
+	"Hack4Change/models"
+	"context"
+)
+
+// loadFolders reads every folder reachable from the project root, parents
+// before children, in one recursive query.
+func (pg *PostQreSQLCon) loadFolders(ctx context.Context, projectID string) ([]models.Folder, error) {
+	query := `WITH RECURSIVE tree AS (
+                  SELECT ` + folderColumns + `, 0 AS depth
+                  FROM folders WHERE project_id = $1 AND parent_folder_id IS NULL
+                  UNION ALL
+                  SELECT f.folder_uid, f.project_id, f.folder_name, f.parent_folder_id, f.created_at, f.updated_at, t.depth + 1
+                  FROM folders f JOIN tree t ON f.parent_folder_id = t.folder_uid
+                  WHERE f.project_id = $1
+              )
+              SELECT ` + folderColumns + ` FROM tree ORDER BY depth, folder_name`
+	rows, err := pg.dbCon.QueryContext(ctx, query, projectID)
+	if err != nil {
+		return nil, mapError(err, "folder")
+	}
+	defer rows.Close()
+
+	var folders []models.Folder
+	for rows.Next() {
+		var folder models.Folder
+		if err := rows.Scan(&folder.ID, &folder.ProjectID, &folder.FolderName, &folder.ParentFolderId, &folder.CreatedAt, &folder.UpdatedAt); err != nil {
+			return nil, mapError(err, "folder")
+		}
+		folders = append(folders, folder)
+	}
+	return folders, mapError(rows.Err(), "folder")
+}
+
+// loadTree reads the project's folders, as loadFolders does, and every
+// file, in two queries. Without withContent the files come back with empty
+// content.
+func (pg *PostQreSQLCon) loadTree(ctx context.Context, projectID string, withContent bool) ([]models.Folder, []models.File, error) {
+	folders, err := pg.loadFolders(ctx, projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fileQuery := `SELECT file_uid, project_id, parent_folder_id, file_name,
+                         CASE WHEN $2 THEN file_content ELSE '' END, version, created_at, updated_at
+                  FROM files WHERE project_id = $1 ORDER BY file_name`
+	rows, err := pg.dbCon.QueryContext(ctx, fileQuery, projectID, withContent)
+	if err != nil {
+		return nil, nil, mapError(err, "file")
+	}
+	defer rows.Close()
+
+	var files []models.File
+	for rows.Next() {
+		var file models.File
+		if err := rows.Scan(&file.ID, &file.ProjectID, &file.ParentFolderId, &file.FileName, &file.FileContent, &file.Version, &file.CreatedAt, &file.UpdatedAt); err != nil {
+			return nil, nil, mapError(err, "file")
+		}
+		files = append(files, file)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, mapError(err, "file")
+	}
+	return folders, files, nil
+}
+
+// GetProjectTree returns the whole project as nested folders.
+func (pg *PostQreSQLCon) GetProjectTree(ctx context.Context, projectID string, withContent bool) (models.ProjectTree, error) {
+	ctx, done := instrument(ctx, "GetProjectTree")
+	defer done()
+
+	folders, files, err := pg.loadTree(ctx, projectID, withContent)
+	if err != nil {
+		return models.ProjectTree{}, err
+	}
+	tree := buildTree(projectID, folders, files)
+	tree.ContentIncluded = withContent
+	return tree, nil
+}
+
+// buildTree nests folders, given parents before children, and files under
+// their parents. Every folder and file list is non-nil so empty ones encode
+// as [].
+func buildTree(projectID string, folders []models.Folder, files []models.File) models.ProjectTree {
+	tree := models.ProjectTree{ProjectID: projectID}
+	tree.Folders, tree.Files = nestFolders(folders, files,
+		func(file models.File) *string { return file.ParentFolderId },
+		func(folder models.Folder, subfolders []models.TreeFolder, files []models.File) models.TreeFolder {
+			return models.TreeFolder{
+				ID:             folder.ID,
+				ProjectID:      folder.ProjectID,
+				FolderName:     folder.FolderName,
+				ParentFolderId: folder.ParentFolderId,
+				CreatedAt:      folder.CreatedAt,
+				UpdatedAt:      folder.UpdatedAt,
+				Folders:        subfolders,
+				Files:          files,
+			}
+		})
+	return tree
+}
+
+// nestFolders does the nesting for a tree of any node and file type: it
+// groups folders, given parents before children, and files under their
+// parents, then builds each folder with node from its nested subfolders and
+// its files, and returns the root folders and root files. Every list passed
+// or returned is non-nil.
+func nestFolders[F, N any](folders []models.Folder, files []F, parentOf func(F) *string, node func(folder models.Folder, subfolders []N, files []F) N) ([]N, []F) {
+	children := make(map[string][]models.Folder, len(folders))
+	filesIn := make(map[string][]F, len(folders))
+	rootFiles := []F{}
+	for _, file := range files {
+		if parent := parentOf(file); parent == nil {
+			rootFiles = append(rootFiles, file)
+		} else {
+			filesIn[*parent] = append(filesIn[*parent], file)
+		}
+	}
+	var roots []models.Folder
+	for _, folder := range folders {
+		if folder.ParentFolderId == nil {
+			roots = append(roots, folder)
+		} else {
+			children[*folder.ParentFolderId] = append(children[*folder.ParentFolderId], folder)
+		}
+	}
+
+	var nest func(folder models.Folder) N
+	nest = func(folder models.Folder) N {
+		subfolders := make([]N, 0, len(children[folder.ID]))
+		for _, child := range children[folder.ID] {
+			subfolders = append(subfolders, nest(child))
+		}
+		folderFiles := filesIn[folder.ID]
+		if folderFiles == nil {
+			folderFiles = []F{}
+		}
+		return node(folder, subfolders, folderFiles)
+	}
+	rootFolders := make([]N, 0, len(roots))
+	for _, root := range roots {
+		rootFolders = append(rootFolders, nest(root))
+	}
+	return rootFolders, rootFiles
+}
+
+// GetProjectStructure returns every folder with the files directly inside
+// it, plus the files at the root: the flat shape /structure has always
+// served.
+func (pg *PostQreSQLCon) GetProjectStructure(ctx context.Context, projectID string) (models.ProjectContents, error) {
+	ctx, done := instrument(ctx, "GetProjectStructure")
+	defer done()
+	var projectContents models.ProjectContents
+
+	folders, files, err := pg.loadTree(ctx, projectID, true)
+	if err != nil {
+		logging.FromContext(ctx).Error("GetProjectStructure: Error loading tree", "projectID", projectID, "error", err)
+		return projectContents, err
+	}
+
+	filesIn := make(map[string][]models.File, len(folders))
+	for _, file := range files {
+		if file.ParentFolderId == nil {
+			projectContents.Files = append(projectContents.Files, file)
+		} else {
+			filesIn[*file.ParentFolderId] = append(filesIn[*file.ParentFolderId], file)
+		}
+	}
+	for _, folder := range folders {
+		projectContents.Folders = append(projectContents.Folders, models.FolderDetails{
+			ID:             folder.ID,
+			ProjectID:      folder.ProjectID,
+			FolderName:     folder.FolderName,
+			ParentFolderId: folder.ParentFolderId,
+			CreatedAt:      folder.CreatedAt,
+			UpdatedAt:      folder.UpdatedAt,
+			Files:          filesIn[folder.ID],
+		})
+	}
+	return projectContents, nil
+}
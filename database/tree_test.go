@@ -0,0 +1,196 @@
+package database
+
+import (
+	"Hack4Change/models"
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Generated projects fan out benchFanout folders per folder, benchDepth
+// levels deep, with benchFilesPerFolder files in each folder and the root:
+// 1554 folders and 7775 files.
+const (
+	benchFanout         = 6
+	benchDepth          = 4
+	benchFilesPerFolder = 5
+)
+
+// generateProject builds a project's folders, parents before children as
+// loadTree returns them, and its files.
+func generateProject(projectID string) ([]models.Folder, []models.File) {
+	now := time.Now()
+	var folders []models.Folder
+	var files []models.File
+	addFiles := func(parent *string) {
+		for i := 0; i < benchFilesPerFolder; i++ {
+			files = append(files, models.File{
+				ID: uuid.New().String(), ProjectID: projectID, ParentFolderId: parent,
+				FileName: fmt.Sprintf("file%d.go", i), FileContent: "package main\n\nfunc main() {}\n",
+				Version: 1, CreatedAt: now, UpdatedAt: now,
+			})
+		}
+	}
+
+	addFiles(nil)
+	level := []*string{nil}
+	for depth := 0; depth < benchDepth; depth++ {
+		var next []*string
+		for _, parent := range level {
+			for i := 0; i < benchFanout; i++ {
+				id := uuid.New().String()
+				folders = append(folders, models.Folder{
+					ID: id, ProjectID: projectID, FolderName: fmt.Sprintf("dir%d", i),
+					ParentFolderId: parent, CreatedAt: now, UpdatedAt: now,
+				})
+				addFiles(&id)
+				next = append(next, &id)
+			}
+		}
+		level = next
+	}
+	return folders, files
+}
+
+func countTree(folders []models.TreeFolder) (nFolders, nFiles int) {
+	for _, folder := range folders {
+		f, n := countTree(folder.Folders)
+		nFolders += 1 + f
+		nFiles += len(folder.Files) + n
+	}
+	return nFolders, nFiles
+}
+
+// TestBuildTrees checks that the full and meta trees nest the same project
+// the same way.
+func TestBuildTrees(t *testing.T) {
+	projectID := uuid.New().String()
+	folders, files := generateProject(projectID)
+	metas := make([]models.FileMeta, len(files))
+	for i, file := range files {
+		metas[i] = models.FileMeta{ID: file.ID, ParentFolderId: file.ParentFolderId, FileName: file.FileName}
+	}
+
+	tree := buildTree(projectID, folders, files)
+	meta := buildMetaTree(projectID, folders, metas)
+	var compare func(path string, full []models.TreeFolder, meta []models.MetaTreeFolder)
+	compare = func(path string, full []models.TreeFolder, meta []models.MetaTreeFolder) {
+		if len(full) != len(meta) {
+			t.Fatalf("%s: %d folders in the tree, %d in the meta tree", path, len(full), len(meta))
+		}
+		for i := range full {
+			f, m := full[i], meta[i]
+			if f.ID != m.ID || len(f.Files) != len(m.Files) || m.ChildCount != len(f.Folders)+len(f.Files) {
+				t.Fatalf("%s/%s: tree has %d folders and %d files, meta tree %s has %d files and child count %d",
+					path, f.FolderName, len(f.Folders), len(f.Files), m.FolderName, len(m.Files), m.ChildCount)
+			}
+			compare(path+"/"+f.FolderName, f.Folders, m.Folders)
+		}
+	}
+	compare("", tree.Folders, meta.Folders)
+	if len(tree.Files) != benchFilesPerFolder || len(meta.Files) != benchFilesPerFolder {
+		t.Errorf("root holds %d and %d files, want %d", len(tree.Files), len(meta.Files), benchFilesPerFolder)
+	}
+	nFolders, nFiles := countTree(tree.Folders)
+	if nFolders != len(folders) || nFiles+len(tree.Files) != len(files) {
+		t.Errorf("tree holds %d folders and %d files, want %d and %d", nFolders, nFiles+len(tree.Files), len(folders), len(files))
+	}
+
+	empty := buildMetaTree(projectID, nil, nil)
+	if empty.Folders == nil || empty.Files == nil {
+		t.Errorf("empty meta tree has nil lists: %+v", empty)
+	}
+}
+
+func BenchmarkBuildTree(b *testing.B) {
+	projectID := uuid.New().String()
+	folders, files := generateProject(projectID)
+
+	tree := buildTree(projectID, folders, files)
+	nFolders, nFiles := countTree(tree.Folders)
+	if nFolders != len(folders) || nFiles+len(tree.Files) != len(files) {
+		b.Fatalf("tree holds %d folders and %d files, want %d and %d", nFolders, nFiles+len(tree.Files), len(folders), len(files))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buildTree(projectID, folders, files)
+	}
+}
+
+// BenchmarkGetProjectTree runs against the database named by
+// TEST_DATABASE_URL, which it migrates and writes a throwaway project to.
+func BenchmarkGetProjectTree(b *testing.B) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		b.Skip("TEST_DATABASE_URL is not set")
+	}
+	ctx := context.Background()
+	pg, err := ConnectPostgreSQL(dsn)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer pg.Close()
+	if _, err := pg.MigrateUp(ctx); err != nil {
+		b.Fatal(err)
+	}
+
+	userID, projectID := uuid.New().String(), uuid.New().String()
+	user := models.UserDetails{ID: userID, Username: "bench-" + userID[:8], Email: "bench-" + userID[:8] + "@example.com"}
+	if err := pg.InsertUser(ctx, user, "x"); err != nil {
+		b.Fatal(err)
+	}
+	// Deleting the user cascades to the project and everything in it.
+	defer pg.dbCon.ExecContext(ctx, `DELETE FROM users WHERE user_uid = $1`, userID)
+	if err := pg.InsertProject(ctx, models.ProjectDetails{ProjectID: projectID, OwnerID: userID, ProjectName: "bench"}); err != nil {
+		b.Fatal(err)
+	}
+
+	folders, files := generateProject(projectID)
+	tx, err := pg.dbCon.BeginTxx(ctx, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, folder := range folders {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO folders (folder_uid, project_id, folder_name, parent_folder_id) VALUES ($1, $2, $3, $4)`,
+			folder.ID, projectID, folder.FolderName, nullableID(folder.ParentFolderId)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	for _, file := range files {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO files (file_uid, project_id, file_name, file_content, parent_folder_id) VALUES ($1, $2, $3, $4, $5)`,
+			file.ID, projectID, file.FileName, file.FileContent, nullableID(file.ParentFolderId)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("tree", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := pg.GetProjectTree(ctx, projectID, true); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("tree-without-content", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := pg.GetProjectTree(ctx, projectID, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("structure", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := pg.GetProjectStructure(ctx, projectID); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
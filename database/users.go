@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// FetchUserRole returns the role of an active user.
+func (pg *PostQreSQLCon) FetchUserRole(ctx context.Context, userID string) (string, error) {
+	ctx, done := instrument(ctx, "FetchUserRole")
+	defer done()
+
+	var role string
+	query := `SELECT role FROM users WHERE user_uid = $1 AND deleted_at IS NULL`
+	err := pg.dbCon.QueryRowContext(ctx, query, userID).Scan(&role)
+	return role, mapError(err, "user")
+}
+
+// SetUserRole changes a user's role; see models.RoleUser and RoleAdmin.
+func (pg *PostQreSQLCon) SetUserRole(ctx context.Context, userID, role string) error {
+	ctx, done := instrument(ctx, "SetUserRole")
+	defer done()
+
+	query := `UPDATE users SET role = $1, updated_at = NOW() WHERE user_uid = $2 AND deleted_at IS NULL`
+	res, err := pg.dbCon.ExecContext(ctx, query, role, userID)
+	return expectRows(res, err, "user")
+}
+
+// UpdatePasswordHash replaces a user's bcrypt password hash.
+func (pg *PostQreSQLCon) UpdatePasswordHash(ctx context.Context, userID, passwordHash string) error {
+	ctx, done := instrument(ctx, "UpdatePasswordHash")
+	defer done()
+
+	query := `UPDATE users SET password_hash = $1, updated_at = NOW() WHERE user_uid = $2 AND deleted_at IS NULL`
+	res, err := pg.dbCon.ExecContext(ctx, query, passwordHash, userID)
+	return expectRows(res, err, "user")
+}
+
+// SoftDeleteUser marks an active account deleted. It can no longer sign in
+// and is removed for good by PurgeDeletedUsers once old enough.
+func (pg *PostQreSQLCon) SoftDeleteUser(ctx context.Context, userID string) error {
+	ctx, done := instrument(ctx, "SoftDeleteUser")
+	defer done()
+
+	query := `UPDATE users SET deleted_at = NOW(), updated_at = NOW() WHERE user_uid = $1 AND deleted_at IS NULL`
+	res, err := pg.dbCon.ExecContext(ctx, query, userID)
+	return expectRows(res, err, "user")
+}
+
+// CountDeletedUsers counts accounts soft-deleted before cutoff.
+func (pg *PostQreSQLCon) CountDeletedUsers(ctx context.Context, cutoff time.Time) (int64, error) {
+	ctx, done := instrument(ctx, "CountDeletedUsers")
+	defer done()
+
+	var count int64
+	query := `SELECT COUNT(*) FROM users WHERE deleted_at IS NOT NULL AND deleted_at < $1`
+	err := pg.dbCon.QueryRowContext(ctx, query, cutoff).Scan(&count)
+	return count, mapError(err, "user")
+}
+
+// PurgeDeletedUsers permanently removes accounts soft-deleted before cutoff.
+// Their projects, files, folders and socials go with them via ON DELETE
+// CASCADE.
+func (pg *PostQreSQLCon) PurgeDeletedUsers(ctx context.Context, cutoff time.Time) (int64, error) {
+	ctx, done := instrument(ctx, "PurgeDeletedUsers")
+	defer done()
+
+	query := `DELETE FROM users WHERE deleted_at IS NOT NULL AND deleted_at < $1`
+	res, err := pg.dbCon.ExecContext(ctx, query, cutoff)
+	if err != nil {
+		return 0, mapError(err, "user")
+	}
+	return res.RowsAffected()
+}
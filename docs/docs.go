@@ -0,0 +1,16 @@
+package docs
+
+import (
+	_ "embed"
+)
+
+// OpenAPI is the OpenAPI 3 document describing every route registered in
+// routes.InitializeRoutes. routes_test.go fails if a route is missing.
+//
+//go:embed openapi.yaml
+var OpenAPI []byte
+
+// UI is a Swagger UI page that renders OpenAPI from /openapi.yaml.
+//
+//go:embed index.html
+var UI []byte
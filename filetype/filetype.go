@@ -0,0 +1,82 @@
+// Package filetype classifies space files by name.
+package filetype
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+var byExtension = map[string]string{
+	".c":     "c",
+	".h":     "c",
+	".cc":    "cpp",
+	".cpp":   "cpp",
+	".hpp":   "cpp",
+	".cs":    "csharp",
+	".css":   "css",
+	".dart":  "dart",
+	".go":    "go",
+	".html":  "html",
+	".htm":   "html",
+	".java":  "java",
+	".js":    "javascript",
+	".mjs":   "javascript",
+	".cjs":   "javascript",
+	".jsx":   "javascript",
+	".json":  "json",
+	".kt":    "kotlin",
+	".lua":   "lua",
+	".md":    "markdown",
+	".php":   "php",
+	".py":    "python",
+	".rb":    "ruby",
+	".rs":    "rust",
+	".scss":  "scss",
+	".sh":    "shell",
+	".bash":  "shell",
+	".sql":   "sql",
+	".swift": "swift",
+	".toml":  "toml",
+	".ts":    "typescript",
+	".tsx":   "typescript",
+	".txt":   "plaintext",
+	".xml":   "xml",
+	".yaml":  "yaml",
+	".yml":   "yaml",
+}
+
+var byName = map[string]string{
+	"dockerfile": "dockerfile",
+	"makefile":   "makefile",
+	"go.mod":     "go.mod",
+	"go.sum":     "go.sum",
+}
+
+// Language returns the editor language id for a file name, or "plaintext"
+// when the name says nothing more specific.
+func Language(name string) string {
+	lower := strings.ToLower(name)
+	if lang, ok := byName[lower]; ok {
+		return lang
+	}
+	if lang, ok := byExtension[path.Ext(lower)]; ok {
+		return lang
+	}
+	return "plaintext"
+}
+
+// SniffLen is how far into a file IsBinary looks for a NUL byte, as git
+// does.
+const SniffLen = 8000
+
+// IsBinary reports whether content looks like binary data rather than text:
+// it has a NUL byte near the start or is not valid UTF-8.
+func IsBinary(content []byte) bool {
+	head := content
+	if len(head) > SniffLen {
+		head = head[:SniffLen]
+	}
+	return bytes.IndexByte(head, 0) >= 0 || !utf8.Valid(content)
+}
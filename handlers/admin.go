@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"Hack4Change/apperrors"
+	"Hack4Change/database"
+	"Hack4Change/models"
+	"Hack4Change/validation"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// resetTokenTTL is how long a dry run's confirmation token stays valid.
+const resetTokenTTL = 5 * time.Minute
+
+// ResetData truncates the requested entities, plus every table that
+// references them. A dry run reports the row counts and returns a
+// confirmation token bound to the caller and the exact table set; the real
+// reset only proceeds with a valid, unexpired token, and each token works
+// once. Resets are recorded in the admin audit log. The route is only
+// mounted outside production.
+func ResetData(c *gin.Context, db *database.PostQreSQLCon, secret []byte) {
+	var req models.ResetDataReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger(c).Warn("ResetData failed: Invalid request", "error", err)
+		abortWithError(c, validation.Error(err))
+		return
+	}
+
+	tables, err := database.ExpandResetEntities(req.Entities)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	actorID := c.GetString("userID")
+
+	if req.DryRun {
+		rows, err := db.CountRows(c.Request.Context(), tables)
+		if err != nil {
+			logger(c).Error("ResetData failed: Error counting rows", "tables", tables, "error", err)
+			abortWithError(c, err)
+			return
+		}
+		expiresAt := time.Now().Add(resetTokenTTL).UTC().Truncate(time.Second)
+		token, err := resetToken(secret, actorID, tables, expiresAt)
+		if err != nil {
+			logger(c).Error("ResetData failed: Error creating confirmation token", "error", err)
+			abortWithError(c, apperrors.Internal(err))
+			return
+		}
+		logger(c).Info("Reset dry run", "tables", tables, "rows", rows)
+		respond(c, http.StatusOK, models.ResetDataRes{
+			DryRun:            true,
+			Tables:            tables,
+			Rows:              rows,
+			ConfirmationToken: token,
+			ExpiresAt:         &expiresAt,
+		})
+		return
+	}
+
+	if !validResetToken(secret, req.ConfirmationToken, actorID, tables) {
+		abortWithError(c, apperrors.Validation("Invalid or expired confirmation token").
+			WithField("confirmation_token", "run a dry run with the same entities and pass its confirmation_token within 5 minutes"))
+		return
+	}
+
+	tokenHash := sha256.Sum256([]byte(req.ConfirmationToken))
+	rows, err := db.ResetTables(c.Request.Context(), actorID, hex.EncodeToString(tokenHash[:]), tables)
+	if err != nil {
+		logger(c).Error("ResetData failed: Error truncating tables", "tables", tables, "error", err)
+		abortWithError(c, err)
+		return
+	}
+	logger(c).Warn("Data reset", "tables", tables, "rows", rows)
+	respond(c, http.StatusOK, models.ResetDataRes{Tables: tables, Rows: rows})
+}
+
+// resetToken is "<expiry unix>.<nonce>.<mac>", the MAC covering the actor,
+// the table set, the expiry and the nonce. The nonce keeps tokens from two
+// dry runs in the same second apart, since each can only be used once.
+func resetToken(secret []byte, actorID string, tables []string, expiresAt time.Time) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+	nonce := base64.RawURLEncoding.EncodeToString(b)
+	return expiry + "." + nonce + "." + base64.RawURLEncoding.EncodeToString(resetMAC(secret, actorID, tables, expiry, nonce)), nil
+}
+
+// validResetToken checks the token's MAC and expiry. Decoding is strict so
+// that a token has exactly one spelling, which is what makes its hash
+// usable to spot reuse.
+func validResetToken(secret []byte, token, actorID string, tables []string) bool {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	expiry, nonce, sig := parts[0], parts[1], parts[2]
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || time.Now().After(time.Unix(unix, 0)) {
+		return false
+	}
+	mac, err := base64.RawURLEncoding.Strict().DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(mac, resetMAC(secret, actorID, tables, expiry, nonce))
+}
+
+func resetMAC(secret []byte, actorID string, tables []string, expiry, nonce string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte("data.reset\n" + actorID + "\n" + strings.Join(tables, ",") + "\n" + expiry + "\n" + nonce))
+	return h.Sum(nil)
+}
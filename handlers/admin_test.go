@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResetToken(t *testing.T) {
+	secret := []byte("secret")
+	tables := []string{"files", "folders", "projects"}
+	expiresAt := time.Now().Add(resetTokenTTL)
+
+	token, err := resetToken(secret, "admin", tables, expiresAt)
+	if err != nil {
+		t.Fatalf("resetToken() error = %v", err)
+	}
+	other, err := resetToken(secret, "admin", tables, expiresAt)
+	if err != nil {
+		t.Fatalf("resetToken() error = %v", err)
+	}
+	if token == other {
+		t.Error("two dry runs in the same second got the same token")
+	}
+	expired, err := resetToken(secret, "admin", tables, time.Now().Add(-time.Second))
+	if err != nil {
+		t.Fatalf("resetToken() error = %v", err)
+	}
+	parts := strings.Split(token, ".")
+
+	tests := []struct {
+		name    string
+		secret  string
+		token   string
+		actorID string
+		tables  []string
+		want    bool
+	}{
+		{name: "valid", token: token, want: true},
+		{name: "another valid token", token: other, want: true},
+		{name: "expired", token: expired},
+		{name: "other actor", token: token, actorID: "someone"},
+		{name: "other tables", token: token, tables: []string{"projects"}},
+		{name: "other secret", token: token, secret: "guess"},
+		{name: "swapped nonce", token: parts[0] + "." + strings.Split(other, ".")[1] + "." + parts[2]},
+		{name: "extended expiry", token: "9999999999." + parts[1] + "." + parts[2]},
+		{name: "padded signature", token: token + "="},
+		{name: "old format", token: parts[0] + "." + parts[2]},
+		{name: "empty", token: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, actorID, tbls := secret, "admin", tables
+			if tt.secret != "" {
+				s = []byte(tt.secret)
+			}
+			if tt.actorID != "" {
+				actorID = tt.actorID
+			}
+			if tt.tables != nil {
+				tbls = tt.tables
+			}
+			if got := validResetToken(s, tt.token, actorID, tbls); got != tt.want {
+				t.Errorf("validResetToken(%q) = %v, want %v", tt.token, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"Hack4Change/apperrors"
+	"Hack4Change/database"
+	"Hack4Change/metrics"
+	"Hack4Change/models"
+	"Hack4Change/spacearchive"
+	"Hack4Change/validation"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ExportSpace streams the space as a zip, or a tar.gz with ?format=tar.gz.
+func ExportSpace(c *gin.Context, db *database.PostQreSQLCon) {
+	projectID := c.Param("id")
+	format := c.DefaultQuery("format", spacearchive.FormatZip)
+	if format != spacearchive.FormatZip && format != spacearchive.FormatTarGz {
+		abortWithError(c, apperrors.Validation("Invalid query").WithField("format", "must be zip or tar.gz"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	project, err := db.FetchProject(ctx, projectID)
+	if err != nil {
+		logger(c).Error("ExportSpace failed: Error fetching project", "projectID", projectID, "error", err)
+		abortWithError(c, err)
+		return
+	}
+	tree, err := db.GetProjectTree(ctx, projectID, true)
+	if err != nil {
+		logger(c).Error("ExportSpace failed: Error fetching tree", "projectID", projectID, "error", err)
+		abortWithError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", spacearchive.ContentType(format))
+	c.Header("Content-Disposition", `attachment; filename="`+archiveName(project.ProjectName)+"."+format+`"`)
+	c.Status(http.StatusOK)
+	if err := spacearchive.Write(c.Writer, format, spacearchive.FromProject(tree), project.UpdatedAt); err != nil {
+		// The archive is already streaming, so all that is left is to cut
+		// it short; the client sees a truncated download.
+		logger(c).Error("ExportSpace failed: Error writing archive", "projectID", projectID, "error", err)
+		c.Abort()
+		return
+	}
+	logger(c).Info("Space exported successfully", "projectID", projectID, "format", format)
+}
+
+// ImportSpace creates a space from an uploaded zip or tar.gz archive sent as
+// the request body. Binary files and symlinks are skipped and listed in the
+// response.
+func ImportSpace(c *gin.Context, db *database.PostQreSQLCon, limits spacearchive.Limits) {
+	var req models.ImportSpaceReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		logger(c).Error("ImportSpace failed: Invalid request", "error", err)
+		abortWithError(c, validation.Error(err))
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		logger(c).Error("ImportSpace failed: Error reading body", "error", err)
+		abortWithError(c, validation.Error(err))
+		return
+	}
+	format := req.Format
+	if format == "" {
+		detected, ok := spacearchive.DetectFormat(body)
+		if !ok {
+			abortWithError(c, apperrors.Validation("Unrecognised archive").WithField("format", "body is neither zip nor tar.gz"))
+			return
+		}
+		format = detected
+	}
+
+	tree, err := spacearchive.Read(body, format, limits)
+	if err != nil {
+		logger(c).Info("ImportSpace rejected: Invalid archive", "error", err)
+		abortWithError(c, err)
+		return
+	}
+	if len(tree.Files) == 0 && len(tree.Dirs) == 0 {
+		abortWithError(c, apperrors.Validation("Archive has nothing to import").WithField("archive", "contains no text files or folders"))
+		return
+	}
+
+	project := models.ProjectDetails{
+		ProjectID:          uuid.New().String(),
+		OwnerID:            c.GetString("userID"),
+		ProjectName:        req.ProjectName,
+		ProjectDescription: req.ProjectDescription,
+	}
+	if err := db.ImportProject(c.Request.Context(), project, tree, c.GetString("userID")); err != nil {
+		logger(c).Error("ImportSpace failed: Error creating space", "error", err)
+		abortWithError(c, err)
+		return
+	}
+
+	metrics.SpacesCreated.Inc()
+	logger(c).Info("Space imported successfully", "projectID", project.ProjectID, "folders", len(tree.Dirs), "files", len(tree.Files), "skipped", len(tree.Skipped))
+	skipped := tree.Skipped
+	if skipped == nil {
+		skipped = []models.SkippedEntry{}
+	}
+	respond(c, http.StatusCreated, models.ImportSpaceRes{
+		ProjectID: project.ProjectID,
+		Folders:   len(tree.Dirs),
+		Files:     len(tree.Files),
+		Skipped:   skipped,
+	})
+}
+
+// archiveName makes a project name safe for a Content-Disposition filename.
+func archiveName(name string) string {
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '-'
+	}, name)
+	safe = strings.Trim(safe, "-.")
+	if safe == "" {
+		return "space-" + time.Now().UTC().Format("20060102")
+	}
+	return safe
+}
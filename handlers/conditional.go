@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"Hack4Change/database"
+	"Hack4Change/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondCacheable is respond for read endpoints that support conditional
+// requests. The ETag is a hash of the exact bytes sent, so it changes with
+// the content and with the API version's envelope. A matching If-None-Match,
+// or failing that an If-Modified-Since no older than lastModified, gets an
+// empty 304. A zero lastModified omits Last-Modified.
+func respondCacheable(c *gin.Context, status int, body any, lastModified time.Time) {
+	payload, err := json.Marshal(envelopeFor(c)(c, status, body))
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	sum := sha256.Sum256(payload)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	h := c.Writer.Header()
+	h.Set("ETag", etag)
+	// Responses are per user: browsers may keep them but must revalidate,
+	// and shared caches must key on the credentials.
+	h.Set("Cache-Control", "private, no-cache")
+	h.Add("Vary", "Authorization")
+	if !lastModified.IsZero() {
+		h.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(c.Request, etag, lastModified) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(status, "application/json; charset=utf-8", payload)
+}
+
+// notModified applies the RFC 9110 precedence: If-None-Match wins, and
+// If-Modified-Since is only consulted when it is absent.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, etag)
+	}
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// etagMatches does the weak comparison If-None-Match calls for, so a W/
+// prefix added by an intermediary still matches.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// projectUpdatedAt reads when the project last changed, which every
+// listing's Last-Modified starts from: deletes, renames and moves leave no
+// row behind to date them, but they do bump the project. It is read before
+// the listing, since a date older than the content only costs a refetch
+// while a newer one could hide a change. On failure it aborts the request.
+func projectUpdatedAt(c *gin.Context, db *database.PostQreSQLCon, projectID string) (time.Time, bool) {
+	updatedAt, err := db.ProjectUpdatedAt(c.Request.Context(), projectID)
+	if err != nil {
+		logger(c).Error("Error fetching project update time", "projectID", projectID, "error", err)
+		abortWithError(c, err)
+		return time.Time{}, false
+	}
+	return updatedAt, true
+}
+
+func filesLastModified(latest time.Time, files []models.File) time.Time {
+	for _, f := range files {
+		if f.UpdatedAt.After(latest) {
+			latest = f.UpdatedAt
+		}
+	}
+	return latest
+}
+
+func structureLastModified(projectUpdatedAt time.Time, contents models.ProjectContents) time.Time {
+	latest := filesLastModified(projectUpdatedAt, contents.Files)
+	for _, folder := range contents.Folders {
+		if folder.UpdatedAt.After(latest) {
+			latest = folder.UpdatedAt
+		}
+		latest = filesLastModified(latest, folder.Files)
+	}
+	return latest
+}
+
+func treeLastModified(projectUpdatedAt time.Time, tree models.ProjectTree) time.Time {
+	latest := filesLastModified(projectUpdatedAt, tree.Files)
+	var walk func(folders []models.TreeFolder)
+	walk = func(folders []models.TreeFolder) {
+		for _, folder := range folders {
+			if folder.UpdatedAt.After(latest) {
+				latest = folder.UpdatedAt
+			}
+			latest = filesLastModified(latest, folder.Files)
+			walk(folder.Folders)
+		}
+	}
+	walk(tree.Folders)
+	return latest
+}
+
+func metaTreeLastModified(projectUpdatedAt time.Time, tree models.MetaTree) time.Time {
+	latest := fileMetaLastModified(projectUpdatedAt, tree.Files)
+	var walk func(folders []models.MetaTreeFolder)
+	walk = func(folders []models.MetaTreeFolder) {
+		for _, folder := range folders {
+			if folder.UpdatedAt.After(latest) {
+				latest = folder.UpdatedAt
+			}
+			latest = fileMetaLastModified(latest, folder.Files)
+			walk(folder.Folders)
+		}
+	}
+	walk(tree.Folders)
+	return latest
+}
+
+func childrenLastModified(projectUpdatedAt time.Time, listing models.FolderChildren) time.Time {
+	latest := fileMetaLastModified(projectUpdatedAt, listing.Files)
+	for _, folder := range listing.Folders {
+		if folder.UpdatedAt.After(latest) {
+			latest = folder.UpdatedAt
+		}
+	}
+	return latest
+}
+
+func fileMetaLastModified(latest time.Time, files []models.FileMeta) time.Time {
+	for _, f := range files {
+		if f.UpdatedAt.After(latest) {
+			latest = f.UpdatedAt
+		}
+	}
+	return latest
+}
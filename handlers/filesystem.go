@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"Hack4Change/database"
+	"Hack4Change/models"
+	"Hack4Change/validation"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RenameFile(c *gin.Context, db *database.PostQreSQLCon) {
+	var req models.RenameReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger(c).Error("RenameFile failed: Invalid request", "error", err)
+		abortWithError(c, validation.Error(err))
+		return
+	}
+
+	projectID, fileID := c.Param("id"), c.Param("fileId")
+	file, err := db.RenameFile(c.Request.Context(), projectID, fileID, req.Name)
+	if err != nil {
+		logger(c).Error("RenameFile failed: Error renaming file", "fileID", fileID, "error", err)
+		abortWithError(c, err)
+		return
+	}
+
+	logger(c).Info("File renamed successfully", "fileID", fileID)
+	respond(c, http.StatusOK, gin.H{"data": file})
+}
+
+func MoveFile(c *gin.Context, db *database.PostQreSQLCon) {
+	var req models.MoveReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger(c).Error("MoveFile failed: Invalid request", "error", err)
+		abortWithError(c, validation.Error(err))
+		return
+	}
+
+	projectID, fileID := c.Param("id"), c.Param("fileId")
+	file, err := db.MoveFile(c.Request.Context(), projectID, fileID, req.ParentFolderId)
+	if err != nil {
+		logger(c).Error("MoveFile failed: Error moving file", "fileID", fileID, "error", err)
+		abortWithError(c, err)
+		return
+	}
+
+	logger(c).Info("File moved successfully", "fileID", fileID, "parentFolderID", file.ParentFolderId)
+	respond(c, http.StatusOK, gin.H{"data": file})
+}
+
+func DeleteFile(c *gin.Context, db *database.PostQreSQLCon) {
+	projectID, fileID := c.Param("id"), c.Param("fileId")
+	if err := db.DeleteFile(c.Request.Context(), projectID, fileID); err != nil {
+		logger(c).Error("DeleteFile failed: Error deleting file", "fileID", fileID, "error", err)
+		abortWithError(c, err)
+		return
+	}
+
+	logger(c).Info("File deleted successfully", "fileID", fileID)
+	respond(c, http.StatusOK, gin.H{"message": "success"})
+}
+
+func RenameFolder(c *gin.Context, db *database.PostQreSQLCon) {
+	var req models.RenameReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger(c).Error("RenameFolder failed: Invalid request", "error", err)
+		abortWithError(c, validation.Error(err))
+		return
+	}
+
+	projectID, folderID := c.Param("id"), c.Param("folderId")
+	folder, err := db.RenameFolder(c.Request.Context(), projectID, folderID, req.Name)
+	if err != nil {
+		logger(c).Error("RenameFolder failed: Error renaming folder", "folderID", folderID, "error", err)
+		abortWithError(c, err)
+		return
+	}
+
+	logger(c).Info("Folder renamed successfully", "folderID", folderID)
+	respond(c, http.StatusOK, gin.H{"data": folder})
+}
+
+func MoveFolder(c *gin.Context, db *database.PostQreSQLCon) {
+	var req models.MoveReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger(c).Error("MoveFolder failed: Invalid request", "error", err)
+		abortWithError(c, validation.Error(err))
+		return
+	}
+
+	projectID, folderID := c.Param("id"), c.Param("folderId")
+	folder, err := db.MoveFolder(c.Request.Context(), projectID, folderID, req.ParentFolderId)
+	if err != nil {
+		logger(c).Error("MoveFolder failed: Error moving folder", "folderID", folderID, "error", err)
+		abortWithError(c, err)
+		return
+	}
+
+	logger(c).Info("Folder moved successfully", "folderID", folderID, "parentFolderID", folder.ParentFolderId)
+	respond(c, http.StatusOK, gin.H{"data": folder})
+}
+
+// DeleteFolder deletes a folder together with everything beneath it.
+func DeleteFolder(c *gin.Context, db *database.PostQreSQLCon) {
+	projectID, folderID := c.Param("id"), c.Param("folderId")
+	folders, files, err := db.DeleteFolder(c.Request.Context(), projectID, folderID)
+	if err != nil {
+		logger(c).Error("DeleteFolder failed: Error deleting folder", "folderID", folderID, "error", err)
+		abortWithError(c, err)
+		return
+	}
+
+	logger(c).Info("Folder deleted successfully", "folderID", folderID, "folders", folders, "files", files)
+	respond(c, http.StatusOK, models.DeleteFolderRes{
+		Message:        "success",
+		DeletedFolders: folders,
+		DeletedFiles:   files,
+	})
+}
+
+// GetFileContent serves a file's raw content. It honours Range and
+// If-Range, so large files can be fetched in pieces, and its ETag is the
+// file version.
+func GetFileContent(c *gin.Context, db *database.PostQreSQLCon) {
+	projectID, fileID := c.Param("id"), c.Param("fileId")
+
+	file, err := db.FetchFile(c.Request.Context(), projectID, fileID)
+	if err != nil {
+		logger(c).Error("GetFileContent failed: Error fetching file", "fileID", fileID, "error", err)
+		abortWithError(c, err)
+		return
+	}
+
+	h := c.Writer.Header()
+	h.Set("ETag", versionETag(file.Version))
+	h.Set("Content-Type", "text/plain; charset=utf-8")
+	h.Set("Cache-Control", "private, no-cache")
+	h.Add("Vary", "Authorization")
+	// ServeContent handles the conditional and range headers, including
+	// 206, 304 and 416 responses.
+	http.ServeContent(c.Writer, c.Request, file.FileName, file.UpdatedAt, strings.NewReader(file.FileContent))
+}
@@ -1,13 +1,16 @@
 package handlers
 
 import (
+	"Hack4Change/ai"
+	"Hack4Change/apperrors"
 	"Hack4Change/database"
 	"Hack4Change/helpers"
-	"bytes"
-	"encoding/json"
+	"Hack4Change/logging"
+	"Hack4Change/metrics"
 	"log/slog"
 
 	"Hack4Change/models"
+	"Hack4Change/validation"
 
 	"net/http"
 	"time"
@@ -16,74 +19,64 @@ import (
 	"github.com/google/uuid"
 )
 
-func CreateTables(c *gin.Context, db *database.PostQreSQLCon) {
-	if err := db.CreateTables(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	c.JSON(http.StatusOK, gin.H{"message": "succcessful"})
-}
-
 func Login(c *gin.Context, db *database.PostQreSQLCon) {
 	var login models.Login
-	if err := c.BindJSON(&login); err != nil {
-		slog.Error("Login failed: Invalid request", "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+	if err := c.ShouldBindJSON(&login); err != nil {
+		logger(c).Error("Login failed: Invalid request", "error", err)
+		abortWithError(c, validation.Error(err))
 		return
 	}
 
-	hashedPassword, err := db.FetchHashedPassword(login.Email)
+	hashedPassword, err := db.FetchHashedPassword(c.Request.Context(), login.Email)
 	if err != nil {
-		slog.Error("Login failed: Error fetching hashed password", "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if apperrors.Is(err, apperrors.KindNotFound) {
+			logger(c).Warn("Login failed: Unknown email", "email", login.Email)
+			abortWithError(c, apperrors.Unauthorized("Invalid email or password"))
+			return
+		}
+		logger(c).Error("Login failed: Error fetching hashed password", "error", err)
+		abortWithError(c, err)
 		return
 	}
-	slog.Info("FETCHED PASSWORD", "hashed password", hashedPassword)
 
 	check := helpers.CheckPasswordHash(hashedPassword, login.Password)
 	if !check {
-		slog.Warn("Login failed: Invalid password", "email", login.Email)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid password"})
+		logger(c).Warn("Login failed: Invalid password", "email", login.Email)
+		abortWithError(c, apperrors.Unauthorized("Invalid email or password"))
 		return
 	}
 
-	userID, err := db.FetchUserIdByEmail(login.Email)
+	userID, err := db.FetchUserIdByEmail(c.Request.Context(), login.Email)
 	if err != nil {
-		slog.Error("Login failed: Error fetching user ID", "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		logger(c).Error("Login failed: Error fetching user ID", "error", err)
+		abortWithError(c, err)
 		return
 	}
 
 	token, err := helpers.GenerateJWT(userID)
 	if err != nil {
-		slog.Error("Login failed: Error generating JWT", "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		logger(c).Error("Login failed: Error generating JWT", "error", err)
+		abortWithError(c, apperrors.Internal(err))
 		return
 	}
 
-	slog.Info("Login successful", "userID", userID)
-	c.JSON(http.StatusOK, gin.H{"token": token, "userID": userID})
+	logger(c).Info("Login successful", "userID", userID)
+	respond(c, http.StatusOK, gin.H{"token": token, "userID": userID})
 }
 
 func Register(c *gin.Context, dbConn *database.PostQreSQLCon) {
 	var payload models.CreateAccountReq
 	if err := c.ShouldBindJSON(&payload); err != nil {
-		slog.Error("Registration failed: Invalid request", "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	if payload.Password != payload.ConfirmPassword {
-		slog.Warn("Registration failed: Passwords do not match", "email", payload.Email)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Passwords do not match"})
+		logger(c).Error("Registration failed: Invalid request", "error", err)
+		abortWithError(c, validation.Error(err))
 		return
 	}
 
 	userID := uuid.New().String()
 	passwordHash, err := helpers.HashPassword(payload.Password)
 	if err != nil {
-		slog.Error("Registration failed: Error hashing password", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		logger(c).Error("Registration failed: Error hashing password", "error", err)
+		abortWithError(c, apperrors.Internal(err))
 		return
 	}
 
@@ -98,36 +91,37 @@ func Register(c *gin.Context, dbConn *database.PostQreSQLCon) {
 		UpdatedAt: time.Now(),
 	}
 
-	err = dbConn.InsertUser(user, passwordHash)
+	err = dbConn.InsertUser(c.Request.Context(), user, passwordHash)
 	if err != nil {
-		slog.Error("Registration failed: Error inserting user into database", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to insert user into database"})
+		logger(c).Error("Registration failed: Error inserting user into database", "error", err)
+		abortWithError(c, err)
 		return
 	}
 
 	token, err := helpers.GenerateJWT(userID)
 	if err != nil {
-		slog.Error("Registration failed: Error generating JWT", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		logger(c).Error("Registration failed: Error generating JWT", "error", err)
+		abortWithError(c, apperrors.Internal(err))
 		return
 	}
 
-	slog.Info("Registration successful", "userID", userID)
-	c.JSON(http.StatusOK, gin.H{"token": token, "userId": userID})
+	metrics.Registrations.Inc()
+	logger(c).Info("Registration successful", "userID", userID)
+	respond(c, http.StatusOK, gin.H{"token": token, "userId": userID})
 }
 
 func CreateProject(c *gin.Context, dbCon *database.PostQreSQLCon) {
 	var payload models.CreateProjectReq
 	if err := c.ShouldBindJSON(&payload); err != nil {
-		slog.Error("CreateProject failed: Invalid request", "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		logger(c).Error("CreateProject failed: Invalid request", "error", err)
+		abortWithError(c, validation.Error(err))
 		return
 	}
 
 	userId, exist := c.Get("userID")
 	if !exist {
-		slog.Warn("CreateProject failed: Unauthorized access")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		logger(c).Warn("CreateProject failed: Unauthorized access")
+		abortWithError(c, apperrors.Unauthorized("Unauthorized"))
 		return
 	}
 
@@ -142,29 +136,30 @@ func CreateProject(c *gin.Context, dbCon *database.PostQreSQLCon) {
 		UpdatedAt:          time.Now(),
 	}
 
-	err := dbCon.InsertProject(project)
+	err := dbCon.InsertProject(c.Request.Context(), project)
 	if err != nil {
-		slog.Error("CreateProject failed: Error inserting project", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger(c).Error("CreateProject failed: Error inserting project", "error", err)
+		abortWithError(c, err)
 		return
 	}
 
-	slog.Info("Project created successfully", "projectID", projectID)
-	c.JSON(http.StatusOK, gin.H{"message": "success"})
+	metrics.SpacesCreated.Inc()
+	logger(c).Info("Project created successfully", "projectID", projectID)
+	respond(c, http.StatusOK, gin.H{"message": "success"})
 }
 
 func CreateFile(c *gin.Context, dbCon *database.PostQreSQLCon) {
 	var payload models.CreateFileReq
 	if err := c.ShouldBindJSON(&payload); err != nil {
-		slog.Error("CreateFile failed: Invalid request", "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		logger(c).Error("CreateFile failed: Invalid request", "error", err)
+		abortWithError(c, validation.Error(err))
 		return
 	}
 
-	_, exist := c.Get("userID")
+	userID, exist := c.Get("userID")
 	if !exist {
-		slog.Warn("CreateFile failed: Unauthorized access")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		logger(c).Warn("CreateFile failed: Unauthorized access")
+		abortWithError(c, apperrors.Unauthorized("Unauthorized"))
 		return
 	}
 
@@ -184,29 +179,29 @@ func CreateFile(c *gin.Context, dbCon *database.PostQreSQLCon) {
 		UpdatedAt:      time.Now(),
 	}
 
-	err := dbCon.InsertFile(file)
+	err := dbCon.InsertFile(c.Request.Context(), file, userID.(string))
 	if err != nil {
-		slog.Error("CreateFile failed: Error inserting file", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger(c).Error("CreateFile failed: Error inserting file", "error", err)
+		abortWithError(c, err)
 		return
 	}
 
-	slog.Info("File created successfully", "fileID", fileID)
-	c.JSON(http.StatusOK, gin.H{"message": "success"})
+	logger(c).Info("File created successfully", "fileID", fileID)
+	respond(c, http.StatusOK, gin.H{"message": "success"})
 }
 
 func CreateFolder(c *gin.Context, dbCon *database.PostQreSQLCon) {
 	var payload models.CreateFolderReq
 	if err := c.ShouldBindJSON(&payload); err != nil {
-		slog.Error("CreateFolder failed: Invalid request", "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		logger(c).Error("CreateFolder failed: Invalid request", "error", err)
+		abortWithError(c, validation.Error(err))
 		return
 	}
 
 	_, exist := c.Get("userID")
 	if !exist {
-		slog.Warn("CreateFolder failed: Unauthorized access")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		logger(c).Warn("CreateFolder failed: Unauthorized access")
+		abortWithError(c, apperrors.Unauthorized("Unauthorized"))
 		return
 	}
 
@@ -226,139 +221,165 @@ func CreateFolder(c *gin.Context, dbCon *database.PostQreSQLCon) {
 		UpdatedAt:      time.Now(),
 	}
 
-	err := dbCon.InsertFolder(folder)
+	err := dbCon.InsertFolder(c.Request.Context(), folder)
 	if err != nil {
-		slog.Error("CreateFolder failed: Error inserting folder", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger(c).Error("CreateFolder failed: Error inserting folder", "error", err)
+		abortWithError(c, err)
 		return
 	}
 
-	slog.Info("Folder created successfully", "folderID", folderID)
-	c.JSON(http.StatusOK, gin.H{"message": "success"})
+	logger(c).Info("Folder created successfully", "folderID", folderID)
+	respond(c, http.StatusOK, gin.H{"message": "success"})
 }
 
+// SaveFileContent saves a file with optimistic concurrency: the client must
+// name the version it edited, in If-Match or expected_version, and gets 409
+// with the current server copy if someone else saved in the meantime.
 func SaveFileContent(c *gin.Context, db *database.PostQreSQLCon) {
 	var req models.SaveFileRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("SaveFileContent failed: Invalid request", "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		logger(c).Error("SaveFileContent failed: Invalid request", "error", err)
+		abortWithError(c, validation.Error(err))
 		return
 	}
 
-	if err := db.SaveContent(req.Content); err != nil {
-		slog.Error("SaveFileContent failed: Error saving content", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	projectID := c.Param("id")
+
+	expectedVersion, err := saveVersion(c.GetHeader("If-Match"), req.ExpectedVersion)
+	if err != nil {
+		abortWithError(c, err)
 		return
 	}
 
-	slog.Info("File content saved successfully")
-	c.JSON(http.StatusOK, gin.H{"message": "File content saved successfully"})
+	file, err := db.SaveFileContent(c.Request.Context(), projectID, req.FileID.String(), req.Content, expectedVersion, c.GetString("userID"))
+	if err != nil {
+		if apperrors.Is(err, apperrors.KindConflict) {
+			logger(c).Info("SaveFileContent rejected: Version conflict", "fileID", req.FileID, "expectedVersion", expectedVersion)
+		} else {
+			logger(c).Error("SaveFileContent failed: Error saving content", "fileID", req.FileID, "error", err)
+		}
+		abortWithError(c, err)
+		return
+	}
+
+	logger(c).Info("File content saved successfully", "fileID", file.ID, "version", file.Version)
+	c.Header("ETag", versionETag(file.Version))
+	respond(c, http.StatusOK, models.SaveFileRes{
+		Message:   "File content saved successfully",
+		FileID:    file.ID,
+		Version:   file.Version,
+		UpdatedAt: file.UpdatedAt,
+	})
 }
 
 func FetchFilesByProjectId(c *gin.Context, db *database.PostQreSQLCon) {
 	projectID := c.Param("id")
+	updatedAt, ok := projectUpdatedAt(c, db, projectID)
+	if !ok {
+		return
+	}
 
-	fileDetails, err := db.FetchFilesByProjectId(projectID)
+	fileDetails, err := db.FetchFilesByProjectId(c.Request.Context(), projectID)
 	if err != nil {
-		slog.Error("FetchFilesByProjectId failed: Error fetching files", "projectID", projectID, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger(c).Error("FetchFilesByProjectId failed: Error fetching files", "projectID", projectID, "error", err)
+		abortWithError(c, err)
 		return
 	}
 
-	slog.Info("Files fetched successfully", "projectID", projectID)
-	c.JSON(http.StatusOK, gin.H{"data": fileDetails})
+	logger(c).Info("Files fetched successfully", "projectID", projectID)
+	respondCacheable(c, http.StatusOK, gin.H{"data": fileDetails}, filesLastModified(updatedAt, fileDetails))
 }
 
 func FetchFoldersByProjectId(c *gin.Context, db *database.PostQreSQLCon) {
 	projectID := c.Param("id")
 
-	folderDetails, err := db.FetchFoldersByProjectId(projectID)
+	folderDetails, err := db.FetchFoldersByProjectId(c.Request.Context(), projectID)
 	if err != nil {
-		slog.Error("FetchFoldersByProjectId failed: Error fetching folders", "projectID", projectID, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger(c).Error("FetchFoldersByProjectId failed: Error fetching folders", "projectID", projectID, "error", err)
+		abortWithError(c, err)
 		return
 	}
 
-	slog.Info("Folders fetched successfully", "projectID", projectID)
-	c.JSON(http.StatusOK, gin.H{"data": folderDetails})
+	logger(c).Info("Folders fetched successfully", "projectID", projectID)
+	respond(c, http.StatusOK, gin.H{"data": folderDetails})
 }
 
 func FetchProjectsByUserId(c *gin.Context, db *database.PostQreSQLCon) {
 	userId, exists := c.Get("userID")
 	if !exists {
-		slog.Warn("FetchProjectsByUserId failed: Unauthorized access")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		logger(c).Warn("FetchProjectsByUserId failed: Unauthorized access")
+		abortWithError(c, apperrors.Unauthorized("Unauthorized access"))
 		return
 	}
 
-	projectDetails, err := db.FetchProjectsByUserId(userId.(string))
+	projectDetails, err := db.FetchProjectsByUserId(c.Request.Context(), userId.(string))
 	if err != nil {
-		slog.Error("FetchProjectsByUserId failed: Error fetching projects", "userId", userId, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger(c).Error("FetchProjectsByUserId failed: Error fetching projects", "userId", userId, "error", err)
+		abortWithError(c, err)
 		return
 	}
 
-	slog.Info("Projects fetched successfully", "userId", userId)
-	c.JSON(http.StatusOK, gin.H{"data": projectDetails})
+	logger(c).Info("Projects fetched successfully", "userId", userId)
+	respond(c, http.StatusOK, gin.H{"data": projectDetails})
 }
 
 func FetchUserData(c *gin.Context, db *database.PostQreSQLCon) {
 	userId, exists := c.Get("userID")
 	if !exists {
-		slog.Warn("FetchUserData failed: Unauthorized access")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		logger(c).Warn("FetchUserData failed: Unauthorized access")
+		abortWithError(c, apperrors.Unauthorized("Unauthorized access"))
 		return
 	}
 
-	userDetails, err := db.FetchUserDetails(userId.(string))
+	userDetails, err := db.FetchUserDetails(c.Request.Context(), userId.(string))
 	if err != nil {
-		slog.Error("FetchUserData failed: Error fetching user data", "userId", userId, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger(c).Error("FetchUserData failed: Error fetching user data", "userId", userId, "error", err)
+		abortWithError(c, err)
 		return
 	}
 
-	slog.Info("User data fetched successfully", "userId", userId)
-	c.JSON(http.StatusOK, gin.H{"data": userDetails})
+	logger(c).Info("User data fetched successfully", "userId", userId)
+	respondCacheable(c, http.StatusOK, gin.H{"data": userDetails}, userDetails.UpdatedAt)
 }
 
 func Dashboard(c *gin.Context, db *database.PostQreSQLCon) {
 	userId, exists := c.Get("userID")
 	if !exists {
-		slog.Warn("Dashboard failed: Unauthorized access")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		logger(c).Warn("Dashboard failed: Unauthorized access")
+		abortWithError(c, apperrors.Unauthorized("Unauthorized access"))
 		return
 	}
 
-	payload, err := db.FetchSkillIdAndNameByUserID(userId.(string))
+	payload, err := db.FetchSkillIdAndNameByUserID(c.Request.Context(), userId.(string))
 	if err != nil {
-		slog.Error("Dashboard failed: Error fetching skill ID and name", "userId", userId, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger(c).Error("Dashboard failed: Error fetching skill ID and name", "userId", userId, "error", err)
+		abortWithError(c, err)
 		return
 	}
 
-	slog.Info("Dashboard data fetched successfully", "userId", userId)
-	c.JSON(http.StatusOK, gin.H{"data": payload})
+	logger(c).Info("Dashboard data fetched successfully", "userId", userId)
+	respond(c, http.StatusOK, gin.H{"data": payload})
 }
 
 func Status(c *gin.Context, db *database.PostQreSQLCon) {
 	var payload models.StatusReq
 	if err := c.ShouldBindJSON(&payload); err != nil {
-		slog.Error("Status failed: Invalid request", "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Wrong Request"})
+		logger(c).Error("Status failed: Invalid request", "error", err)
+		abortWithError(c, validation.Error(err))
 		return
 	}
 
 	skillId := payload.SkillId
 
-	payload_, err := db.FetchSkillIdAndNameByUserID(skillId)
+	payload_, err := db.FetchSkillIdAndNameByUserID(c.Request.Context(), skillId)
 	if err != nil {
-		slog.Error("Status failed: Error fetching skill details", "skillId", skillId, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger(c).Error("Status failed: Error fetching skill details", "skillId", skillId, "error", err)
+		abortWithError(c, err)
 		return
 	}
 
-	slog.Info("Skill details fetched successfully", "skillId", skillId)
-	c.JSON(http.StatusOK, gin.H{"data": payload_})
+	logger(c).Info("Skill details fetched successfully", "skillId", skillId)
+	respond(c, http.StatusOK, gin.H{"data": payload_})
 }
 
 func SubmitSol(c *gin.Context, db *database.PostQreSQLCon) {
@@ -366,114 +387,69 @@ func SubmitSol(c *gin.Context, db *database.PostQreSQLCon) {
 	skillid := c.Param("id")
 	var payload models.SubmitSolReq
 	if err := c.ShouldBindJSON(&payload); err != nil {
-		slog.Error("SubmitSol failed: Invalid request", "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Wrong Request"})
+		logger(c).Error("SubmitSol failed: Invalid request", "error", err)
+		abortWithError(c, validation.Error(err))
 		return
 	}
 
-	if err := db.SubmitSolutionByQIDandSkillID(qid, skillid); err != nil {
-		slog.Error("SubmitSol failed: Error submitting solution", "qid", qid, "skillid", skillid, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := db.SubmitSolutionByQIDandSkillID(c.Request.Context(), qid, skillid); err != nil {
+		logger(c).Error("SubmitSol failed: Error submitting solution", "qid", qid, "skillid", skillid, "error", err)
+		abortWithError(c, err)
 		return
 	}
 
-	slog.Info("Solution submitted successfully", "qid", qid, "skillid", skillid)
-	c.JSON(http.StatusOK, gin.H{"message": "success"})
+	metrics.SubmissionsReceived.Inc()
+	logger(c).Info("Solution submitted successfully", "qid", qid, "skillid", skillid)
+	respond(c, http.StatusOK, gin.H{"message": "success"})
 }
 
-func GenerateSkill(c *gin.Context, db *database.PostQreSQLCon) {
+func GenerateSkill(c *gin.Context, db *database.PostQreSQLCon, aiClient *ai.Client) {
 	var payload models.GenerateSkillsReq
 	if err := c.ShouldBindJSON(&payload); err != nil {
-		slog.Error("GenerateSkill failed: Invalid request", "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		slog.Error("GenerateSkill failed: Error marshalling payload", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger(c).Error("GenerateSkill failed: Invalid request", "error", err)
+		abortWithError(c, validation.Error(err))
 		return
 	}
 
-	resp, err := http.Post("http://localhost:5868/ai/generate-skill", "application/json", bytes.NewBuffer(jsonData))
+	payloadRes, err := aiClient.GenerateSkill(c.Request.Context(), payload)
 	if err != nil {
-		slog.Error("GenerateSkill failed: Error sending POST request", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger(c).Error("GenerateSkill failed: Error calling AI service", "error", err)
+		abortWithError(c, err)
 		return
 	}
-	defer resp.Body.Close()
 
-	var payloadRes models.SkillRes
-	if err := json.NewDecoder(resp.Body).Decode(&payloadRes); err != nil {
-		slog.Error("GenerateSkill failed: Error decoding response", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	slog.Info("Skill generated successfully")
-	c.JSON(http.StatusOK, gin.H{"data": payloadRes})
+	logger(c).Info("Skill generated successfully")
+	respond(c, http.StatusOK, gin.H{"data": payloadRes})
 }
 
 func BadgeHandler(c *gin.Context, db *database.PostQreSQLCon) {
 	// Implement logging as needed when the function is implemented
 }
 
-func DeleteTables(c *gin.Context, db *database.PostQreSQLCon) {
-	tableName := c.Param("name")
-
-	var err error
-	switch tableName {
-	case "users":
-		err = db.DropUsersTable()
-	case "socials":
-		err = db.DropSocialsTable()
-	case "projects":
-		err = db.DropProjectsTable()
-	case "files":
-		err = db.DropFilesTable()
-	case "folders":
-		err = db.DropFoldersTable()
-	case "skills":
-		err = db.DropSkillsTable()
-	case "all":
-		err = db.DropAllTables()
-	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid table name"})
-		return
-	}
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Table dropped successfully"})
-}
 func UpdateUserProfile(c *gin.Context, db *database.PostQreSQLCon) {
 	userId, exists := c.Get("userID")
 	if !exists {
-		slog.Warn("UpdateUserProfile failed: Unauthorized access")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		logger(c).Warn("UpdateUserProfile failed: Unauthorized access")
+		abortWithError(c, apperrors.Unauthorized("Unauthorized access"))
 		return
 	}
 
 	var socials models.Socials
 	if err := c.ShouldBindJSON(&socials); err != nil {
-		slog.Error("UpdateUserProfile failed: Invalid request payload", "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		logger(c).Error("UpdateUserProfile failed: Invalid request payload", "error", err)
+		abortWithError(c, validation.Error(err))
 		return
 	}
 
-	err := db.UpdateSocialAccounts(userId.(string), socials)
+	err := db.UpdateSocialAccounts(c.Request.Context(), userId.(string), socials)
 	if err != nil {
-		slog.Error("UpdateUserProfile failed: Error updating social accounts", "userId", userId, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger(c).Error("UpdateUserProfile failed: Error updating social accounts", "userId", userId, "error", err)
+		abortWithError(c, err)
 		return
 	}
 
-	slog.Info("User profile updated successfully", "userId", userId)
-	c.JSON(http.StatusOK, gin.H{"message": "User profile updated successfully"})
+	logger(c).Info("User profile updated successfully", "userId", userId)
+	respond(c, http.StatusOK, gin.H{"message": "User profile updated successfully"})
 }
 
 func FetchFilesAndFoldersByProjectId(c *gin.Context, db *database.PostQreSQLCon) {
@@ -481,14 +457,30 @@ func FetchFilesAndFoldersByProjectId(c *gin.Context, db *database.PostQreSQLCon)
 }
 func GetProjectStructureHandler(c *gin.Context, dbCon *database.PostQreSQLCon) {
 	projectID := c.Param("id")
-	slog.Info("Fetching project structure", "projectID", projectID)
+	logger(c).Info("Fetching project structure", "projectID", projectID)
+	updatedAt, ok := projectUpdatedAt(c, dbCon, projectID)
+	if !ok {
+		return
+	}
 
-	projectContents, err := dbCon.GetProjectStructure(projectID)
+	projectContents, err := dbCon.GetProjectStructure(c.Request.Context(), projectID)
 	if err != nil {
-		slog.Error("Failed to fetch project structure", "projectID", projectID, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch project structure"})
+		logger(c).Error("Failed to fetch project structure", "projectID", projectID, "error", err)
+		abortWithError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, projectContents)
+	respondCacheable(c, http.StatusOK, projectContents, structureLastModified(updatedAt, projectContents))
+}
+
+// abortWithError records err for middleware.ErrorHandler, which renders the
+// JSON error envelope, and stops the handler chain.
+func abortWithError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// logger returns the request-scoped logger set up by middleware.Logger.
+func logger(c *gin.Context) *slog.Logger {
+	return logging.FromContext(c.Request.Context())
 }
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"Hack4Change/ai"
+	"Hack4Change/database"
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const readinessCheckTimeout = 2 * time.Second
+
+// healthCheck is one check's result. Failures are logged rather than
+// returned, since the probe is unauthenticated and errors can name hosts
+// and credentials.
+type healthCheck struct {
+	Status  string `json:"status"`
+	Pending *int   `json:"pending,omitempty"`
+}
+
+// Healthz is the liveness probe: it only proves the process can serve HTTP.
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz is the readiness probe. The database must answer a ping and have no
+// pending migrations; the AI service is reported but does not fail the
+// probe, since only the academy generator depends on it. While the server is
+// draining for shutdown the probe fails immediately.
+func Readyz(c *gin.Context, db *database.PostQreSQLCon, aiClient *ai.Client, draining *atomic.Bool) {
+	if draining != nil && draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining"})
+		return
+	}
+
+	ready := true
+	checks := gin.H{}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessCheckTimeout)
+	defer cancel()
+	if err := db.Ping(ctx); err != nil {
+		logger(c).Warn("Readiness: database ping failed", "error", err)
+		checks["database"] = healthCheck{Status: "unavailable"}
+		ready = false
+	} else {
+		checks["database"] = healthCheck{Status: "ok"}
+	}
+
+	pending, err := db.PendingMigrations(ctx)
+	switch {
+	case err != nil:
+		logger(c).Warn("Readiness: migration status failed", "error", err)
+		checks["migrations"] = healthCheck{Status: "unknown"}
+		ready = false
+	case pending > 0:
+		checks["migrations"] = healthCheck{Status: "pending", Pending: &pending}
+		ready = false
+	default:
+		checks["migrations"] = healthCheck{Status: "ok", Pending: &pending}
+	}
+
+	aiCtx, aiCancel := context.WithTimeout(c.Request.Context(), readinessCheckTimeout)
+	defer aiCancel()
+	if err := aiClient.Ping(aiCtx); err != nil {
+		logger(c).Warn("Readiness: AI service ping failed", "error", err)
+		checks["ai_service"] = healthCheck{Status: "unavailable"}
+	} else {
+		checks["ai_service"] = healthCheck{Status: "ok"}
+	}
+
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not_ready", "checks": checks})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready", "checks": checks})
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"Hack4Change/apperrors"
+	"Hack4Change/database"
+	"Hack4Change/models"
+	"Hack4Change/validation"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPathDepth bounds how many folders a path may walk through.
+const maxPathDepth = 64
+
+// spacePath splits a path such as cmd/server/main.go into its folder names
+// and file name, rejecting anything that is not a plain name.
+func spacePath(raw string) ([]string, error) {
+	raw = strings.Trim(raw, "/")
+	if raw == "" {
+		return nil, apperrors.Validation("Invalid path").WithField("path", "must name a file")
+	}
+	segments := strings.Split(raw, "/")
+	if len(segments) > maxPathDepth {
+		return nil, apperrors.Validation("Invalid path").WithField("path", "is nested too deeply")
+	}
+	for _, name := range segments {
+		if !validation.IsSafeFilename(name) {
+			return nil, apperrors.Validation("Invalid path").
+				WithField("path", "each segment must be a valid file name without control characters")
+		}
+	}
+	return segments, nil
+}
+
+// GetFileByPath returns the file at /fs/<path>. Its ETag is the file
+// version, ready to send back as If-Match when writing.
+func GetFileByPath(c *gin.Context, db *database.PostQreSQLCon) {
+	projectID := c.Param("id")
+	path, err := spacePath(c.Param("path"))
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	file, err := db.FetchFileByPath(c.Request.Context(), projectID, path)
+	if err != nil {
+		logger(c).Error("GetFileByPath failed: Error fetching file", "projectID", projectID, "path", c.Param("path"), "error", err)
+		abortWithError(c, err)
+		return
+	}
+
+	etag := versionETag(file.Version)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	c.Header("Vary", "Authorization")
+	c.Header("Last-Modified", file.UpdatedAt.UTC().Format(http.TimeFormat))
+	if notModified(c.Request, etag, file.UpdatedAt) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	respond(c, http.StatusOK, gin.H{"data": file})
+}
+
+// PutFileByPath writes the raw request body to /fs/<path>, creating the
+// file and any missing folders. Replacing an existing file needs its
+// current version in If-Match.
+func PutFileByPath(c *gin.Context, db *database.PostQreSQLCon) {
+	projectID := c.Param("id")
+	path, err := spacePath(c.Param("path"))
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	var expectedVersion *int
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" {
+		v, err := saveVersion(ifMatch, nil)
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+		expectedVersion = &v
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		logger(c).Error("PutFileByPath failed: Error reading body", "error", err)
+		abortWithError(c, validation.Error(err))
+		return
+	}
+	if !utf8.Valid(body) {
+		abortWithError(c, apperrors.Validation("File content must be UTF-8 text").WithField("body", "is not valid UTF-8"))
+		return
+	}
+
+	file, created, err := db.WriteFileByPath(c.Request.Context(), projectID, path, string(body), expectedVersion, c.GetString("userID"))
+	if err != nil {
+		if apperrors.Is(err, apperrors.KindConflict) {
+			logger(c).Info("PutFileByPath rejected: Conflict", "projectID", projectID, "path", c.Param("path"), "error", err)
+		} else {
+			logger(c).Error("PutFileByPath failed: Error writing file", "projectID", projectID, "path", c.Param("path"), "error", err)
+		}
+		abortWithError(c, err)
+		return
+	}
+
+	status, message := http.StatusOK, "File content saved successfully"
+	if created {
+		status, message = http.StatusCreated, "File created successfully"
+	}
+	logger(c).Info(message, "fileID", file.ID, "version", file.Version)
+	c.Header("ETag", versionETag(file.Version))
+	respond(c, status, models.SaveFileRes{
+		Message:   message,
+		FileID:    file.ID,
+		Version:   file.Version,
+		UpdatedAt: file.UpdatedAt,
+	})
+}
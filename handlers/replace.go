@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"Hack4Change/apperrors"
+	"Hack4Change/database"
+	"Hack4Change/models"
+	"Hack4Change/search"
+	"Hack4Change/textdiff"
+	"Hack4Change/validation"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultReplacePreviewFiles is how many files a preview lists when the
+// request sets no limit.
+const defaultReplacePreviewFiles = 100
+
+// maxReplacePreviewBytes bounds the content one preview diffs, counting
+// both sides of each file. Files past it are left out as if over the limit.
+const maxReplacePreviewBytes = 16 << 20
+
+// compileReplace prepares the query of a find-and-replace request.
+func compileReplace(q models.ReplaceQuery) (*search.Matcher, error) {
+	return search.Compile(search.Options{
+		Pattern:    q.Query,
+		Regex:      q.Regex,
+		IgnoreCase: q.IgnoreCase,
+		WholeWord:  q.WholeWord,
+	})
+}
+
+// PreviewReplace shows what a find-and-replace across the space would
+// change, as a diff per file, without changing anything. Each file carries
+// the version the diff was made from, which the apply sends back.
+func PreviewReplace(c *gin.Context, db *database.PostQreSQLCon) {
+	projectID := c.Param("id")
+	var req models.ReplacePreviewReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger(c).Error("PreviewReplace failed: Invalid request", "error", err)
+		abortWithError(c, validation.Error(err))
+		return
+	}
+	matcher, err := compileReplace(req.ReplaceQuery)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultReplacePreviewFiles
+	}
+
+	literal, foldCase := matcher.Literal()
+	files, err := db.ListSearchFiles(c.Request.Context(), projectID, literal, foldCase)
+	if err != nil {
+		logger(c).Error("PreviewReplace failed: Error fetching files", "projectID", projectID, "error", err)
+		abortWithError(c, err)
+		return
+	}
+
+	res := models.ReplacePreviewRes{Files: []models.ReplaceFilePreview{}}
+	diffed := 0
+	for _, file := range files {
+		content, count := matcher.Replace(file.FileContent, req.Replacement)
+		if count == 0 || content == file.FileContent {
+			continue
+		}
+		diffed += len(file.FileContent) + len(content)
+		if len(res.Files) == limit || diffed > maxReplacePreviewBytes {
+			res.Truncated = true
+			break
+		}
+		diff, added, removed := textdiff.Diff("a/"+file.Path, "b/"+file.Path, file.FileContent, content, textdiff.DefaultContext)
+		res.Files = append(res.Files, models.ReplaceFilePreview{
+			FileID:       file.ID,
+			Path:         file.Path,
+			Version:      file.Version,
+			Replacements: count,
+			Added:        added,
+			Removed:      removed,
+			Diff:         diff,
+		})
+		res.Replacements += count
+	}
+
+	respond(c, http.StatusOK, res)
+}
+
+// ApplyReplace performs a previewed find-and-replace on the accepted files,
+// saving each as a new revision in one transaction. If any accepted file
+// is no longer at its previewed version, or no longer matches, nothing is
+// changed and the response lists the stale files.
+func ApplyReplace(c *gin.Context, db *database.PostQreSQLCon) {
+	projectID := c.Param("id")
+	var req models.ReplaceApplyReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger(c).Error("ApplyReplace failed: Invalid request", "error", err)
+		abortWithError(c, validation.Error(err))
+		return
+	}
+	matcher, err := compileReplace(req.ReplaceQuery)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	literal, foldCase := matcher.Literal()
+	files, err := db.ListSearchFiles(c.Request.Context(), projectID, literal, foldCase)
+	if err != nil {
+		logger(c).Error("ApplyReplace failed: Error fetching files", "projectID", projectID, "error", err)
+		abortWithError(c, err)
+		return
+	}
+	edits, counts, err := planReplace(matcher, req.Replacement, req.Files, files)
+	if err != nil {
+		logger(c).Info("ApplyReplace rejected: Files changed since the preview", "projectID", projectID, "error", err)
+		abortWithError(c, err)
+		return
+	}
+
+	saved, err := db.SaveFileEdits(c.Request.Context(), projectID, edits, c.GetString("userID"))
+	if err != nil {
+		logger(c).Error("ApplyReplace failed: Error saving files", "projectID", projectID, "error", err)
+		abortWithError(c, err)
+		return
+	}
+	versions := make(map[string]int, len(saved))
+	for _, file := range saved {
+		versions[file.ID] = file.Version
+	}
+
+	res := models.ReplaceApplyRes{Files: make([]models.ReplacedFile, 0, len(saved))}
+	for _, file := range files {
+		if count, ok := counts[file.ID]; ok {
+			res.Files = append(res.Files, models.ReplacedFile{
+				FileID:       file.ID,
+				Path:         file.Path,
+				Version:      versions[file.ID],
+				Replacements: count,
+			})
+			res.Replacements += count
+		}
+	}
+
+	logger(c).Info("Replace applied", "projectID", projectID, "files", len(res.Files), "replacements", res.Replacements)
+	respond(c, http.StatusOK, res)
+}
+
+// planReplace works out the edit for each accepted file from the files as
+// they are now. The replacement is made again from the current content: a
+// file still at its previewed version has the previewed content, so the
+// result is what the preview showed. If any accepted file has moved on,
+// gone or no longer changes, it returns a conflict listing those files in
+// its details under "stale".
+func planReplace(matcher *search.Matcher, replacement string, accepted []models.ReplaceFileVersion, files []models.PathFile) ([]models.FileEdit, map[string]int, error) {
+	current := make(map[string]models.PathFile, len(files))
+	for _, file := range files {
+		current[file.ID] = file
+	}
+
+	edits := make([]models.FileEdit, 0, len(accepted))
+	counts := make(map[string]int, len(accepted))
+	var stale []models.StaleFile
+	for _, want := range accepted {
+		file, ok := current[want.FileID]
+		content, count := "", 0
+		if ok && file.Version == want.Version {
+			content, count = matcher.Replace(file.FileContent, replacement)
+		}
+		if count == 0 || content == file.FileContent {
+			entry := models.StaleFile{FileID: want.FileID, ExpectedVersion: want.Version}
+			if ok {
+				version := file.Version
+				entry.CurrentVersion = &version
+			}
+			stale = append(stale, entry)
+			continue
+		}
+		edits = append(edits, models.FileEdit{FileID: file.ID, ExpectedVersion: file.Version, Content: content})
+		counts[file.ID] = count
+	}
+	if len(stale) > 0 {
+		return nil, nil, apperrors.Conflict("Files were changed since the preview").
+			WithField("files", strconv.Itoa(len(stale))+" of "+strconv.Itoa(len(accepted))+" no longer match their preview").
+			WithDetails(map[string]any{"stale": stale})
+	}
+	return edits, counts, nil
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"Hack4Change/middleware"
+	"Hack4Change/models"
+	"Hack4Change/search"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func replaceFile(id string, version int, content string) models.PathFile {
+	return models.PathFile{File: models.File{ID: id, Version: version, FileContent: content}, Path: id + ".go"}
+}
+
+func TestPlanReplace(t *testing.T) {
+	matcher, err := search.Compile(search.Options{Pattern: "old"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []models.PathFile{
+		replaceFile("a", 2, "old old\n"),
+		replaceFile("b", 5, "old\n"),
+	}
+
+	edits, counts, err := planReplace(matcher, "new", []models.ReplaceFileVersion{{FileID: "a", Version: 2}}, files)
+	if err != nil {
+		t.Fatalf("planReplace() error = %v", err)
+	}
+	want := []models.FileEdit{{FileID: "a", ExpectedVersion: 2, Content: "new new\n"}}
+	if len(edits) != 1 || edits[0] != want[0] || counts["a"] != 2 {
+		t.Errorf("planReplace() = %+v, %v, want %+v with 2 replacements", edits, counts, want)
+	}
+}
+
+func TestPlanReplaceStale(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	matcher, err := search.Compile(search.Options{Pattern: "old"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []models.PathFile{
+		replaceFile("a", 2, "old\n"),
+		replaceFile("b", 6, "old\n"),
+		replaceFile("c", 3, "new\n"),
+	}
+	accepted := []models.ReplaceFileVersion{
+		{FileID: "a", Version: 2}, // still as previewed
+		{FileID: "b", Version: 5}, // saved since
+		{FileID: "c", Version: 3}, // no longer matches
+		{FileID: "d", Version: 1}, // deleted
+	}
+	_, _, planErr := planReplace(matcher, "new", accepted, files)
+	if planErr == nil {
+		t.Fatal("planReplace() succeeded with stale files")
+	}
+
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.POST("/apply", func(c *gin.Context) { abortWithError(c, planErr) })
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/apply", nil))
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	var body struct {
+		Error struct {
+			Code    string `json:"code"`
+			Details struct {
+				Stale []models.StaleFile `json:"stale"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Error.Code != "conflict" {
+		t.Errorf("code = %q, want conflict", body.Error.Code)
+	}
+	current := func(v int) *int { return &v }
+	wantStale := []models.StaleFile{
+		{FileID: "b", ExpectedVersion: 5, CurrentVersion: current(6)},
+		{FileID: "c", ExpectedVersion: 3, CurrentVersion: current(3)},
+		{FileID: "d", ExpectedVersion: 1},
+	}
+	stale := body.Error.Details.Stale
+	if len(stale) != len(wantStale) {
+		t.Fatalf("details.stale = %+v, want %d files", stale, len(wantStale))
+	}
+	for i, want := range wantStale {
+		got := stale[i]
+		if got.FileID != want.FileID || got.ExpectedVersion != want.ExpectedVersion ||
+			(got.CurrentVersion == nil) != (want.CurrentVersion == nil) ||
+			(got.CurrentVersion != nil && *got.CurrentVersion != *want.CurrentVersion) {
+			t.Errorf("details.stale[%d] = %+v, want %+v", i, got, want)
+		}
+	}
+}
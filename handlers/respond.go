@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"Hack4Change/middleware"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Envelope shapes a successful response body for one API version.
+type Envelope func(c *gin.Context, status int, body any) any
+
+var (
+	envelopesMu sync.RWMutex
+	envelopes   = map[string]Envelope{
+		// v1 keeps the historical shapes untouched.
+		"v1": func(_ *gin.Context, _ int, body any) any { return body },
+	}
+)
+
+// RegisterEnvelope installs the response envelope used for an API version
+// mounted with middleware.APIVersion. Call it before serving.
+func RegisterEnvelope(version string, envelope Envelope) {
+	envelopesMu.Lock()
+	defer envelopesMu.Unlock()
+	envelopes[version] = envelope
+}
+
+// respond writes a successful JSON response using the envelope of the API
+// version the request was routed through.
+func respond(c *gin.Context, status int, body any) {
+	c.JSON(status, envelopeFor(c)(c, status, body))
+}
+
+func envelopeFor(c *gin.Context) Envelope {
+	envelopesMu.RLock()
+	defer envelopesMu.RUnlock()
+	if envelope, ok := envelopes[c.GetString(middleware.APIVersionKey)]; ok {
+		return envelope
+	}
+	return envelopes["v1"]
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"Hack4Change/apperrors"
+	"Hack4Change/database"
+	"Hack4Change/models"
+	"Hack4Change/textdiff"
+	"Hack4Change/validation"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ListFileRevisions returns a file's history, newest first.
+func ListFileRevisions(c *gin.Context, db *database.PostQreSQLCon) {
+	projectID := c.Param("id")
+	fileID, ok := projectFile(c, db)
+	if !ok {
+		return
+	}
+
+	revisions, err := db.ListFileRevisions(c.Request.Context(), projectID, fileID)
+	if err != nil {
+		logger(c).Error("ListFileRevisions failed: Error fetching revisions", "fileID", fileID, "error", err)
+		abortWithError(c, err)
+		return
+	}
+
+	logger(c).Info("File revisions fetched successfully", "fileID", fileID, "count", len(revisions))
+	respondCacheable(c, http.StatusOK, gin.H{"data": revisions}, revisions[0].CreatedAt)
+}
+
+// FetchFileRevision returns a file's content as of one revision.
+func FetchFileRevision(c *gin.Context, db *database.PostQreSQLCon) {
+	projectID := c.Param("id")
+	fileID, ok := projectFile(c, db)
+	if !ok {
+		return
+	}
+	version, err := revisionParam("version", c.Param("version"))
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	revision, err := db.FetchFileRevision(c.Request.Context(), projectID, fileID, version)
+	if err != nil {
+		logger(c).Error("FetchFileRevision failed: Error fetching revision", "fileID", fileID, "version", version, "error", err)
+		abortWithError(c, err)
+		return
+	}
+
+	// Revisions never change, so they are safe to cache like any other read.
+	respondCacheable(c, http.StatusOK, gin.H{"data": revision}, revision.CreatedAt)
+}
+
+// DiffFileRevisions returns a unified diff between two revisions of a file.
+// Either may be the older one; the diff always reads from "from" to "to".
+func DiffFileRevisions(c *gin.Context, db *database.PostQreSQLCon) {
+	projectID := c.Param("id")
+	fileID, ok := projectFile(c, db)
+	if !ok {
+		return
+	}
+	from, err := revisionParam("from", c.Query("from"))
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	to, err := revisionParam("to", c.Query("to"))
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	a, err := db.FetchFileRevision(ctx, projectID, fileID, from)
+	if err != nil {
+		logger(c).Error("DiffFileRevisions failed: Error fetching revision", "fileID", fileID, "version", from, "error", err)
+		abortWithError(c, err)
+		return
+	}
+	b, err := db.FetchFileRevision(ctx, projectID, fileID, to)
+	if err != nil {
+		logger(c).Error("DiffFileRevisions failed: Error fetching revision", "fileID", fileID, "version", to, "error", err)
+		abortWithError(c, err)
+		return
+	}
+
+	diff, added, removed := textdiff.Diff("v"+strconv.Itoa(from), "v"+strconv.Itoa(to), a.Content, b.Content, textdiff.DefaultContext)
+	lastModified := a.CreatedAt
+	if b.CreatedAt.After(lastModified) {
+		lastModified = b.CreatedAt
+	}
+	respondCacheable(c, http.StatusOK, models.FileDiffRes{
+		FileID:  fileID,
+		From:    from,
+		To:      to,
+		Added:   added,
+		Removed: removed,
+		Diff:    diff,
+	}, lastModified)
+}
+
+// RestoreFileRevision saves an earlier revision's content as a new version.
+// Like a save, it needs the version being replaced in If-Match or the body.
+func RestoreFileRevision(c *gin.Context, db *database.PostQreSQLCon) {
+	projectID := c.Param("id")
+	fileID, ok := projectFile(c, db)
+	if !ok {
+		return
+	}
+	version, err := revisionParam("version", c.Param("version"))
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	var req models.RestoreRevisionReq
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			logger(c).Error("RestoreFileRevision failed: Invalid request", "error", err)
+			abortWithError(c, validation.Error(err))
+			return
+		}
+	}
+	expectedVersion, err := saveVersion(c.GetHeader("If-Match"), req.ExpectedVersion)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	file, err := db.RestoreFileRevision(c.Request.Context(), projectID, fileID, version, expectedVersion, c.GetString("userID"))
+	if err != nil {
+		if apperrors.Is(err, apperrors.KindConflict) {
+			logger(c).Info("RestoreFileRevision rejected: Version conflict", "fileID", fileID, "expectedVersion", expectedVersion)
+		} else {
+			logger(c).Error("RestoreFileRevision failed: Error restoring revision", "fileID", fileID, "version", version, "error", err)
+		}
+		abortWithError(c, err)
+		return
+	}
+
+	logger(c).Info("File revision restored successfully", "fileID", file.ID, "restoredFrom", version, "version", file.Version)
+	c.Header("ETag", versionETag(file.Version))
+	respond(c, http.StatusOK, models.SaveFileRes{
+		Message:   "File revision restored successfully",
+		FileID:    file.ID,
+		Version:   file.Version,
+		UpdatedAt: file.UpdatedAt,
+	})
+}
+
+// projectFile returns the :fileId of a revision route once it is known to
+// be a file of the space in :id. The revision queries filter on the space
+// as well, but checking first gives every route the same answer for a file
+// of another space before any revision is read or written.
+func projectFile(c *gin.Context, db *database.PostQreSQLCon) (string, bool) {
+	projectID, fileID := c.Param("id"), c.Param("fileId")
+	if _, err := uuid.Parse(fileID); err != nil {
+		abortWithError(c, apperrors.NotFound("file not found"))
+		return "", false
+	}
+	if err := db.CheckFileInProject(c.Request.Context(), projectID, fileID); err != nil {
+		if !apperrors.Is(err, apperrors.KindNotFound) {
+			logger(c).Error("Error checking the file belongs to the space", "projectID", projectID, "fileID", fileID, "error", err)
+		}
+		abortWithError(c, err)
+		return "", false
+	}
+	return fileID, true
+}
+
+// revisionParam parses a revision number from the path or query.
+func revisionParam(name, value string) (int, error) {
+	v, err := strconv.Atoi(value)
+	if err != nil || v < 1 {
+		return 0, apperrors.Validation("Invalid revision").WithField(name, "must be a positive version number")
+	}
+	return v, nil
+}
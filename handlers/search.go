@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"Hack4Change/database"
+	"Hack4Change/models"
+	"Hack4Change/search"
+	"Hack4Change/validation"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchSpace finds the lines of the space's files that match a text or
+// regex query, grouped by file with context lines around each.
+func SearchSpace(c *gin.Context, db *database.PostQreSQLCon) {
+	projectID := c.Param("id")
+	var req models.SearchReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		logger(c).Error("SearchSpace failed: Invalid request", "error", err)
+		abortWithError(c, validation.Error(err))
+		return
+	}
+	matcher, err := search.Compile(search.Options{
+		Pattern:    req.Query,
+		Regex:      req.Regex,
+		IgnoreCase: req.IgnoreCase,
+		WholeWord:  req.WholeWord,
+	})
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	literal, foldCase := matcher.Literal()
+	files, err := db.ListSearchFiles(c.Request.Context(), projectID, literal, foldCase)
+	if err != nil {
+		logger(c).Error("SearchSpace failed: Error fetching files", "projectID", projectID, "error", err)
+		abortWithError(c, err)
+		return
+	}
+
+	res := models.SearchRes{Files: []models.SearchFileResult{}}
+	for _, file := range files {
+		lines, truncated := matcher.Lines(file.FileContent, req.Context, req.Limit-res.Matches)
+		if len(lines) > 0 {
+			res.Files = append(res.Files, models.SearchFileResult{
+				FileID:  file.ID,
+				Path:    file.Path,
+				Version: file.Version,
+				Lines:   lines,
+			})
+			res.Matches += len(lines)
+		}
+		if truncated {
+			res.Truncated = true
+			break
+		}
+	}
+
+	logger(c).Info("Space searched", "projectID", projectID, "regex", req.Regex, "candidates", len(files), "matches", res.Matches)
+	respond(c, http.StatusOK, res)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"Hack4Change/apperrors"
+	"Hack4Change/database"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetProjectTree returns the space as nested folders. ?content=false
+// leaves out file contents for clients that only render names.
+func GetProjectTree(c *gin.Context, db *database.PostQreSQLCon) {
+	projectID := c.Param("id")
+	withContent, err := strconv.ParseBool(c.DefaultQuery("content", "true"))
+	if err != nil {
+		abortWithError(c, apperrors.Validation("Invalid query").WithField("content", "must be true or false"))
+		return
+	}
+	updatedAt, ok := projectUpdatedAt(c, db, projectID)
+	if !ok {
+		return
+	}
+
+	tree, err := db.GetProjectTree(c.Request.Context(), projectID, withContent)
+	if err != nil {
+		logger(c).Error("GetProjectTree failed: Error fetching tree", "projectID", projectID, "error", err)
+		abortWithError(c, err)
+		return
+	}
+
+	respondCacheable(c, http.StatusOK, gin.H{"data": tree}, treeLastModified(updatedAt, tree))
+}
+
+// GetProjectMetaTree returns the space as nested folders with file names,
+// sizes and languages but no content, enough to render a file browser.
+func GetProjectMetaTree(c *gin.Context, db *database.PostQreSQLCon) {
+	projectID := c.Param("id")
+	updatedAt, ok := projectUpdatedAt(c, db, projectID)
+	if !ok {
+		return
+	}
+
+	tree, err := db.GetProjectMetaTree(c.Request.Context(), projectID)
+	if err != nil {
+		logger(c).Error("GetProjectMetaTree failed: Error fetching tree", "projectID", projectID, "error", err)
+		abortWithError(c, err)
+		return
+	}
+
+	respondCacheable(c, http.StatusOK, gin.H{"data": tree}, metaTreeLastModified(updatedAt, tree))
+}
+
+// ListFolderChildren returns one level of the tree: the folder named by
+// ?folder_id, or the root when it is absent.
+func ListFolderChildren(c *gin.Context, db *database.PostQreSQLCon) {
+	projectID := c.Param("id")
+	var folderID *string
+	if id := c.Query("folder_id"); id != "" {
+		if _, err := uuid.Parse(id); err != nil {
+			abortWithError(c, apperrors.Validation("Invalid query").WithField("folder_id", "must be a valid UUID"))
+			return
+		}
+		folderID = &id
+	}
+	updatedAt, ok := projectUpdatedAt(c, db, projectID)
+	if !ok {
+		return
+	}
+
+	listing, err := db.ListFolderChildren(c.Request.Context(), projectID, folderID)
+	if err != nil {
+		logger(c).Error("ListFolderChildren failed: Error listing folder", "projectID", projectID, "folderID", folderID, "error", err)
+		abortWithError(c, err)
+		return
+	}
+
+	respondCacheable(c, http.StatusOK, gin.H{"data": listing}, childrenLastModified(updatedAt, listing))
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"Hack4Change/apperrors"
+	"strconv"
+	"strings"
+)
+
+// versionETag is the ETag of a single file: its version number.
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// saveVersion resolves the version a save is based on from the If-Match
+// header (`"3"`, `W/"3"` or a bare 3) and the body's expected_version. One
+// of them is required, and they must agree when both are sent.
+func saveVersion(ifMatch string, bodyVersion *int) (int, error) {
+	ifMatch = strings.TrimSpace(ifMatch)
+	if ifMatch == "" {
+		if bodyVersion == nil {
+			return 0, apperrors.PreconditionRequired("Saving requires the version being edited").
+				WithField("expected_version", "send it here or as an If-Match header")
+		}
+		return *bodyVersion, nil
+	}
+
+	v, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`))
+	if err != nil || v < 1 {
+		return 0, apperrors.Validation("Invalid If-Match header").
+			WithField("If-Match", "must be the file version, e.g. \"3\"")
+	}
+	if bodyVersion != nil && *bodyVersion != v {
+		return 0, apperrors.Validation("If-Match and expected_version disagree").
+			WithField("expected_version", "must equal the If-Match version")
+	}
+	return v, nil
+}
@@ -0,0 +1,220 @@
+package logging
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys, normalised to lower case without
+// separators, whose values are never written to the log.
+var sensitiveKeys = map[string]bool{
+	"password":        true,
+	"confirmpassword": true,
+	"passwordhash":    true,
+	"hashedpassword":  true,
+	"hash":            true,
+	"token":           true,
+	"accesstoken":     true,
+	"refreshtoken":    true,
+	"authorization":   true,
+	"cookie":          true,
+	"secret":          true,
+	"jwtsecret":       true,
+	"filecontent":     true,
+	"content":         true,
+}
+
+// sensitiveValue catches secrets logged under innocent keys: bcrypt hashes,
+// bearer tokens and bare JWTs.
+var sensitiveValue = regexp.MustCompile(`\$2[abxy]\$\d{2}\$[./A-Za-z0-9]{53}|(?i)bearer\s+\S+|eyJ[\w-]+\.[\w-]+\.[\w-]+`)
+
+// RedactingHandler wraps another slog.Handler and scrubs sensitive
+// attributes, including ones nested in groups, maps, slices and structs,
+// before they reach it.
+type RedactingHandler struct {
+	next slog.Handler
+}
+
+func NewRedactingHandler(next slog.Handler) *RedactingHandler {
+	return &RedactingHandler{next: next}
+}
+
+func (h *RedactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *RedactingHandler) Handle(ctx context.Context, r slog.Record) error {
+	clean := slog.NewRecord(r.Time, r.Level, redactString(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		clean.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, clean)
+}
+
+func (h *RedactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clean := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		clean[i] = redactAttr(a)
+	}
+	return &RedactingHandler{next: h.next.WithAttrs(clean)}
+}
+
+func (h *RedactingHandler) WithGroup(name string) slog.Handler {
+	return &RedactingHandler{next: h.next.WithGroup(name)}
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	if isSensitiveKey(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindGroup:
+		group := v.Group()
+		clean := make([]any, len(group))
+		for i, ga := range group {
+			clean[i] = redactAttr(ga)
+		}
+		return slog.Group(a.Key, clean...)
+	case slog.KindString:
+		return slog.String(a.Key, redactString(v.String()))
+	case slog.KindAny:
+		return slog.Any(a.Key, redactAny(v.Any()))
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+// maxRedactDepth bounds how far redactAny follows nested values, so a
+// pointer cycle cannot loop forever.
+const maxRedactDepth = 10
+
+func redactAny(v any) any {
+	return redactNested(v, 0)
+}
+
+func redactNested(v any, depth int) any {
+	if depth > maxRedactDepth {
+		return "[TRUNCATED]"
+	}
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case error:
+		return redactString(val.Error())
+	case map[string]any:
+		clean := make(map[string]any, len(val))
+		for k, mv := range val {
+			if isSensitiveKey(k) {
+				clean[k] = redacted
+			} else {
+				clean[k] = redactNested(mv, depth+1)
+			}
+		}
+		return clean
+	case map[string]string:
+		clean := make(map[string]string, len(val))
+		for k, mv := range val {
+			if isSensitiveKey(k) {
+				clean[k] = redacted
+			} else {
+				clean[k] = redactString(mv)
+			}
+		}
+		return clean
+	case string:
+		return redactString(val)
+	case fmt.Stringer:
+		return redactString(val.String())
+	case json.Marshaler, encoding.TextMarshaler, []byte:
+		return v
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return v
+		}
+		return redactNested(rv.Elem().Interface(), depth+1)
+	case reflect.Struct:
+		clean := map[string]any{}
+		redactFields(rv, clean, depth)
+		return clean
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return v
+		}
+		clean := make([]any, rv.Len())
+		for i := range clean {
+			clean[i] = redactNested(rv.Index(i).Interface(), depth+1)
+		}
+		return clean
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return v
+		}
+		clean := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			k := iter.Key().String()
+			if isSensitiveKey(k) {
+				clean[k] = redacted
+			} else {
+				clean[k] = redactNested(iter.Value().Interface(), depth+1)
+			}
+		}
+		return clean
+	}
+	return v
+}
+
+// redactFields copies a struct's exported fields into clean under the names
+// encoding/json would give them, flattening embedded structs the same way.
+// A field is redacted when its Go name or its JSON name is sensitive, so
+// models logged whole are scrubbed like maps and groups.
+func redactFields(rv reflect.Value, clean map[string]any, depth int) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		fv := rv.Field(i)
+		if field.Anonymous && name == "" && fv.Kind() == reflect.Struct {
+			redactFields(fv, clean, depth)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if isSensitiveKey(field.Name) || isSensitiveKey(name) {
+			clean[name] = redacted
+		} else {
+			clean[name] = redactNested(fv.Interface(), depth+1)
+		}
+	}
+}
+
+func redactString(s string) string {
+	return sensitiveValue.ReplaceAllString(s, redacted)
+}
+
+func isSensitiveKey(key string) bool {
+	normalised := strings.NewReplacer("_", "", "-", "", " ", "", ".", "").Replace(strings.ToLower(key))
+	return sensitiveKeys[normalised]
+}
@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"Hack4Change/models"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+type credentials struct {
+	User   string
+	Secret string `json:"api_key_secret_field"`
+	Token  string `json:"t"`
+}
+
+type node struct {
+	Name string
+	Next *node
+}
+
+func logJSON(t *testing.T, args ...any) map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	slog.New(NewRedactingHandler(slog.NewJSONHandler(&buf, nil))).Info("msg", args...)
+	var out map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("decoding %s: %v", buf.String(), err)
+	}
+	return out
+}
+
+func TestRedactStructs(t *testing.T) {
+	when := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	out := logJSON(t,
+		"req", models.CreateAccountReq{Username: "ada", Password: "hunter22", ConfirmPassword: "hunter22"},
+		"file", &models.File{ID: "f1", FileName: "main.go", FileContent: "package main", UpdatedAt: when},
+		"creds", []credentials{{User: "ada", Secret: "s3cret", Token: "tok"}},
+		"byID", map[int]string{1: "kept as is"},
+	)
+
+	req := out["req"].(map[string]any)
+	if req["username"] != "ada" || req["password"] != redacted || req["confirm_password"] != redacted {
+		t.Errorf("req = %v, want username kept and both passwords redacted", req)
+	}
+
+	file := out["file"].(map[string]any)
+	if file["file_name"] != "main.go" || file["file_content"] != redacted {
+		t.Errorf("file = %v, want file_content redacted", file)
+	}
+	if file["updated_at"] != when.String() {
+		t.Errorf("file updated_at = %v, want the time kept", file["updated_at"])
+	}
+
+	creds := out["creds"].([]any)[0].(map[string]any)
+	// Secret and Token are caught by their Go names; their JSON names are
+	// not sensitive.
+	if creds["User"] != "ada" || creds["api_key_secret_field"] != redacted || creds["t"] != redacted {
+		t.Errorf("creds = %v, want Secret and Token redacted", creds)
+	}
+
+	if _, ok := out["byID"].(map[string]any); !ok {
+		t.Errorf("byID = %v, want it logged", out["byID"])
+	}
+}
+
+func TestRedactStructValues(t *testing.T) {
+	out := logJSON(t, "note", struct{ Note string }{Note: "Authorization: Bearer abc.def"})
+	note := out["note"].(map[string]any)["Note"].(string)
+	if strings.Contains(note, "abc.def") {
+		t.Errorf("note = %q, want the bearer token redacted", note)
+	}
+}
+
+func TestRedactCycle(t *testing.T) {
+	n := &node{Name: "a"}
+	n.Next = n
+	out := logJSON(t, "node", n)
+	if out["node"].(map[string]any)["Name"] != "a" {
+		t.Errorf("node = %v", out["node"])
+	}
+}
@@ -1,25 +1,24 @@
 package main
 
 import (
-	"log"
-	"log/slog"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
-	db "Hack4Change/database"
-	routes "Hack4Change/routes"
-
-	"github.com/gin-gonic/gin"
-	
+	"Hack4Change/cli"
+	"Hack4Change/config"
 )
 
 func main() {
-	dbConn, err := db.ConnectPostgreSQL()
-	if err != nil {
-		slog.Error("Error with postgresql", slog.String("error", err.Error()))
-		slog.Error("P")
-		log.Fatalf("Error with postgresql: %v", err)
-	}
+	cfg := config.Load()
 
-	router := gin.Default()
-	routes.InitializeRoutes(router, dbConn)
-	router.Run(":7563")
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := cli.Run(ctx, cfg, os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
 }
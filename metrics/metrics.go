@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "hack4change"
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Duration of storage calls by storage method.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"method"})
+
+	AIRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ai_request_duration_seconds",
+		Help:      "Latency of calls to the AI service by operation and outcome.",
+		Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 20, 30, 60},
+	}, []string{"operation", "outcome"})
+
+	AIRequestFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ai_request_failures_total",
+		Help:      "Failed calls to the AI service by operation and reason.",
+	}, []string{"operation", "reason"})
+
+	Registrations = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registrations_total",
+		Help:      "Successful user registrations.",
+	})
+
+	SpacesCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "spaces_created_total",
+		Help:      "Spaces (projects) created.",
+	})
+
+	SubmissionsReceived = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "submissions_received_total",
+		Help:      "Academy solutions submitted. Submissions are recorded, not judged.",
+	})
+)
+
+// Handler serves the default registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// RegisterDBStats exports connection pool statistics for db. Registering the
+// same pool name twice is a no-op.
+func RegisterDBStats(db *sql.DB, name string) error {
+	err := prometheus.Register(collectors.NewDBStatsCollector(db, name))
+	var already prometheus.AlreadyRegisteredError
+	if errors.As(err, &already) {
+		return nil
+	}
+	return err
+}
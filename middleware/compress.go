@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// CompressOptions configures response compression. Bodies shorter than
+// MinSize bytes, or whose media type is not in ContentTypes, are sent as is.
+type CompressOptions struct {
+	MinSize      int
+	ContentTypes []string
+}
+
+var (
+	gzipWriters   = sync.Pool{New: func() any { return gzip.NewWriter(io.Discard) }}
+	brotliWriters = sync.Pool{New: func() any { return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression) }}
+)
+
+// Compress encodes responses with brotli or gzip, whichever the client's
+// Accept-Encoding prefers (brotli on a tie). The body is buffered until
+// MinSize bytes are written so small responses skip compression entirely.
+// Responses that already carry a Content-Encoding, such as /metrics, pass
+// through untouched. Strong ETags are weakened on compressed responses
+// since the bytes on the wire no longer match them.
+func Compress(opts CompressOptions) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(opts.ContentTypes))
+	for _, ct := range opts.ContentTypes {
+		allowed[strings.ToLower(ct)] = true
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		w := &compressWriter{ResponseWriter: c.Writer, encoding: encoding, minSize: opts.MinSize, allowed: allowed}
+		c.Writer = w
+		defer func() {
+			w.Close()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// negotiateEncoding picks "br", "gzip" or "" from an Accept-Encoding value.
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q <= 0 || (name != "br" && name != "gzip") {
+			continue
+		}
+		if q > bestQ || (q == bestQ && name == "br") {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	minSize  int
+	allowed  map[string]bool
+
+	buf     bytes.Buffer
+	decided bool
+	encoder io.WriteCloser
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		if !w.compressible() {
+			w.decide(false)
+		} else if w.buf.Len()+len(b) < w.minSize {
+			return w.buf.Write(b)
+		} else {
+			w.decide(true)
+		}
+	}
+	if w.encoder != nil {
+		return w.encoder.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Written reports buffered output too, so code running between the handler
+// and Close sees the response as started.
+func (w *compressWriter) Written() bool {
+	return w.buf.Len() > 0 || w.ResponseWriter.Written()
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(w.compressible() && w.buf.Len() > 0)
+	}
+	if f, ok := w.encoder.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.Hijack()
+}
+
+// Close sends whatever is still buffered and finishes the encoded stream.
+func (w *compressWriter) Close() {
+	if !w.decided {
+		if w.buf.Len() == 0 {
+			return
+		}
+		w.decide(false)
+	}
+	if w.encoder == nil {
+		return
+	}
+	_ = w.encoder.Close()
+	switch enc := w.encoder.(type) {
+	case *gzip.Writer:
+		enc.Reset(io.Discard)
+		gzipWriters.Put(enc)
+	case *brotli.Writer:
+		enc.Reset(io.Discard)
+		brotliWriters.Put(enc)
+	}
+	w.encoder = nil
+}
+
+func (w *compressWriter) compressible() bool {
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	status := w.Status()
+	// A byte range is a slice of the identity representation; encoding it
+	// would hand the client bytes that match no Content-Range.
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusPartialContent || status == http.StatusNotModified {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	return err == nil && w.allowed[mediaType]
+}
+
+// decide fixes whether the response is compressed and writes out anything
+// buffered so far.
+func (w *compressWriter) decide(compress bool) {
+	w.decided = true
+	if compress {
+		h := w.Header()
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+		switch w.encoding {
+		case "br":
+			enc := brotliWriters.Get().(*brotli.Writer)
+			enc.Reset(w.ResponseWriter)
+			w.encoder = enc
+		default:
+			enc := gzipWriters.Get().(*gzip.Writer)
+			enc.Reset(w.ResponseWriter)
+			w.encoder = enc
+		}
+	}
+	if w.buf.Len() == 0 {
+		return
+	}
+	pending := w.buf.Bytes()
+	w.buf = bytes.Buffer{}
+	if w.encoder != nil {
+		_, _ = w.encoder.Write(pending)
+	} else {
+		_, _ = w.ResponseWriter.Write(pending)
+	}
+}
@@ -0,0 +1,223 @@
+package middleware
+
+import (
+	"Hack4Change/validation"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"deflate", ""},
+		{"*", ""},
+		{"gzip", "gzip"},
+		{"GZIP", "gzip"},
+		{"br", "br"},
+		{"gzip, deflate, br", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"gzip;q=0.8, br;q=0.9", "br"},
+		{"br;q=0, gzip;q=0.1", "gzip"},
+		{"br;q=0", ""},
+		{"gzip;q=bogus", "gzip"},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const minSize = 1024
+	large := strings.Repeat(`{"name":"main.go"},`, 200)
+	small := `{"name":"main.go"}`
+
+	r := gin.New()
+	r.Use(Compress(CompressOptions{MinSize: minSize, ContentTypes: []string{"application/json", "text/plain"}}))
+	r.GET("/json", func(c *gin.Context) {
+		body := large
+		if c.Query("size") == "small" {
+			body = small
+		}
+		if etag := c.Query("etag"); etag != "" {
+			c.Header("ETag", etag)
+		}
+		c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(body))
+	})
+	r.GET("/png", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", []byte(large))
+	})
+	r.GET("/encoded", func(c *gin.Context) {
+		c.Header("Content-Encoding", "gzip")
+		c.Data(http.StatusOK, "text/plain", []byte(large))
+	})
+	r.GET("/status/:code", func(c *gin.Context) {
+		c.Header("ETag", `"v1"`)
+		switch c.Param("code") {
+		case "204":
+			c.Status(http.StatusNoContent)
+		case "206":
+			c.Header("Content-Range", "bytes 0-1999/5000")
+			c.Data(http.StatusPartialContent, "text/plain", []byte(large[:2000]))
+		case "304":
+			c.Status(http.StatusNotModified)
+		}
+	})
+
+	get := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(t *testing.T, w *httptest.ResponseRecorder) string {
+		t.Helper()
+		var rd io.Reader
+		switch enc := w.Header().Get("Content-Encoding"); enc {
+		case "":
+			return w.Body.String()
+		case "gzip":
+			gz, err := gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatalf("gzip.NewReader: %v", err)
+			}
+			rd = gz
+		case "br":
+			rd = brotli.NewReader(w.Body)
+		default:
+			t.Fatalf("unexpected Content-Encoding %q", enc)
+		}
+		b, err := io.ReadAll(rd)
+		if err != nil {
+			t.Fatalf("decoding %s body: %v", w.Header().Get("Content-Encoding"), err)
+		}
+		return string(b)
+	}
+
+	tests := []struct {
+		name         string
+		path         string
+		accept       string
+		wantEncoding string
+		wantBody     string
+		wantETag     string
+	}{
+		{name: "gzip", path: "/json", accept: "gzip", wantEncoding: "gzip", wantBody: large},
+		{name: "brotli preferred on a tie", path: "/json", accept: "gzip, br", wantEncoding: "br", wantBody: large},
+		{name: "client prefers gzip", path: "/json", accept: "br;q=0.1, gzip", wantEncoding: "gzip", wantBody: large},
+		{name: "no Accept-Encoding", path: "/json", wantBody: large},
+		{name: "unsupported encoding only", path: "/json", accept: "deflate", wantBody: large},
+		{name: "under the size threshold", path: "/json?size=small", accept: "gzip", wantBody: small},
+		{name: "media type not listed", path: "/png", accept: "gzip", wantBody: large},
+		{name: "already encoded", path: "/encoded", accept: "br", wantEncoding: "gzip"},
+		{name: "strong ETag weakened", path: `/json?etag="v7"`, accept: "gzip", wantEncoding: "gzip", wantBody: large, wantETag: `W/"v7"`},
+		{name: "weak ETag kept", path: `/json?etag=W/"v7"`, accept: "gzip", wantEncoding: "gzip", wantBody: large, wantETag: `W/"v7"`},
+		{name: "ETag kept when not compressed", path: `/json?size=small&etag="v7"`, accept: "gzip", wantBody: small, wantETag: `"v7"`},
+		{name: "204 skipped", path: "/status/204", accept: "gzip", wantETag: `"v1"`},
+		{name: "206 skipped", path: "/status/206", accept: "gzip", wantBody: large[:2000], wantETag: `"v1"`},
+		{name: "304 skipped", path: "/status/304", accept: "gzip", wantETag: `"v1"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := get(tt.path, tt.accept)
+			if got := w.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if !strings.Contains(strings.Join(w.Header().Values("Vary"), ","), "Accept-Encoding") {
+				t.Errorf("Vary = %q, want Accept-Encoding", w.Header().Values("Vary"))
+			}
+			if tt.wantEncoding != "" && w.Header().Get("Content-Length") != "" {
+				t.Errorf("compressed response kept Content-Length %q", w.Header().Get("Content-Length"))
+			}
+			if tt.path == "/encoded" {
+				return
+			}
+			if got := decode(t, w); got != tt.wantBody {
+				t.Errorf("body = %.40q (%d bytes), want %.40q (%d bytes)", got, len(got), tt.wantBody, len(tt.wantBody))
+			}
+			if tt.wantETag != "" {
+				if got := w.Header().Get("ETag"); got != tt.wantETag {
+					t.Errorf("ETag = %q, want %q", got, tt.wantETag)
+				}
+			}
+		})
+	}
+}
+
+func TestDecompressBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const limit = 64 << 10
+	r := gin.New()
+	r.Use(ErrorHandler())
+	r.POST("/upload", MaxBodySize(limit), DecompressBody(), func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			_ = c.Error(validation.Error(err))
+			c.Abort()
+			return
+		}
+		c.String(http.StatusOK, "%d", len(body))
+	})
+
+	gzipped := func(b []byte) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write(b)
+		gz.Close()
+		return buf.Bytes()
+	}
+	// A megabyte of zeros squeezes into about a kilobyte, far under the
+	// limit on the wire.
+	bomb := gzipped(make([]byte, 1<<20))
+	if len(bomb) >= limit {
+		t.Fatalf("bomb is %d bytes compressed, want it under the %d byte limit", len(bomb), limit)
+	}
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		want     int
+		wantBody string
+	}{
+		{name: "identity", body: []byte("hello"), want: http.StatusOK, wantBody: "5"},
+		{name: "gzip", encoding: "gzip", body: gzipped([]byte("hello")), want: http.StatusOK, wantBody: "5"},
+		{name: "gzip up to the limit", encoding: "x-gzip", body: gzipped(make([]byte, limit)), want: http.StatusOK, wantBody: "65536"},
+		{name: "gzip bomb", encoding: "gzip", body: bomb, want: http.StatusRequestEntityTooLarge},
+		{name: "not gzip", encoding: "gzip", body: []byte("hello"), want: http.StatusBadRequest},
+		{name: "unsupported encoding", encoding: "br", body: []byte("hello"), want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	corsAllowMethods  = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+	corsAllowHeaders  = "Authorization, Content-Type, X-Request-ID, Idempotency-Key, If-Match, If-None-Match, If-Modified-Since, Range, If-Range"
+	corsExposeHeaders = "X-Request-ID, ETag, RateLimit-Policy, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After, Deprecation, Sunset, Link, Idempotent-Replayed, Accept-Ranges, Content-Range"
+)
+
+// CORSOptions configures the CORS middleware. An origin of "*" allows any
+// origin; with AllowCredentials the request's own origin is echoed instead,
+// since browsers reject a wildcard on credentialed requests.
+type CORSOptions struct {
+	AllowedOrigins   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// CORS answers preflight requests and adds Access-Control-* headers for
+// allowed origins. Requests from other origins are served without CORS
+// headers, so the browser blocks them.
+func CORS(opts CORSOptions) gin.HandlerFunc {
+	allowAny := false
+	allowed := make(map[string]bool, len(opts.AllowedOrigins))
+	for _, origin := range opts.AllowedOrigins {
+		if origin == "*" {
+			allowAny = true
+		}
+		allowed[strings.TrimSuffix(origin, "/")] = true
+	}
+	maxAge := strconv.Itoa(int(opts.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Origin")
+
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if !allowAny && !allowed[origin] {
+			if preflight {
+				c.AbortWithStatus(http.StatusNoContent)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if allowAny && !opts.AllowCredentials {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		if opts.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			c.Header("Access-Control-Allow-Methods", corsAllowMethods)
+			c.Header("Access-Control-Allow-Headers", corsAllowHeaders)
+			if opts.MaxAge > 0 {
+				c.Header("Access-Control-Max-Age", maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Header("Access-Control-Expose-Headers", corsExposeHeaders)
+		c.Next()
+	}
+}
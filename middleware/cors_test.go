@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(opts CORSOptions) *gin.Engine {
+		r := gin.New()
+		r.Use(CORS(opts))
+		r.GET("/space/details", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+		return r
+	}
+	listed := newRouter(CORSOptions{AllowedOrigins: []string{"https://app.example.com/"}, MaxAge: 10 * time.Minute})
+	anyOrigin := newRouter(CORSOptions{AllowedOrigins: []string{"*"}})
+	credentialed := newRouter(CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+
+	tests := []struct {
+		name        string
+		router      *gin.Engine
+		method      string
+		origin      string
+		preflight   bool
+		wantStatus  int
+		wantOrigin  string
+		wantMaxAge  string
+		wantMethods bool
+		wantExpose  bool
+		wantCreds   bool
+	}{
+		{
+			name: "preflight from an allowed origin", router: listed, origin: "https://app.example.com", preflight: true,
+			wantStatus: http.StatusNoContent, wantOrigin: "https://app.example.com", wantMaxAge: "600", wantMethods: true,
+		},
+		{
+			name: "preflight from another origin", router: listed, origin: "https://evil.example.net", preflight: true,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name: "request from an allowed origin", router: listed, origin: "https://app.example.com",
+			wantStatus: http.StatusOK, wantOrigin: "https://app.example.com", wantExpose: true,
+		},
+		{
+			name: "request from another origin", router: listed, origin: "https://evil.example.net",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "request without an origin", router: listed,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "wildcard", router: anyOrigin, origin: "https://anywhere.example.org", preflight: true,
+			wantStatus: http.StatusNoContent, wantOrigin: "*", wantMethods: true,
+		},
+		{
+			name: "wildcard with credentials echoes the origin", router: credentialed, origin: "https://anywhere.example.org",
+			wantStatus: http.StatusOK, wantOrigin: "https://anywhere.example.org", wantExpose: true, wantCreds: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := http.MethodGet
+			if tt.preflight {
+				method = http.MethodOptions
+			}
+			req := httptest.NewRequest(method, "/space/details", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+				req.Header.Set("Access-Control-Request-Headers", "Authorization")
+			}
+			w := httptest.NewRecorder()
+			tt.router.ServeHTTP(w, req)
+
+			h := w.Header()
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := h.Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := h.Get("Access-Control-Max-Age"); got != tt.wantMaxAge {
+				t.Errorf("Access-Control-Max-Age = %q, want %q", got, tt.wantMaxAge)
+			}
+			if got := h.Get("Access-Control-Allow-Methods") != ""; got != tt.wantMethods {
+				t.Errorf("Access-Control-Allow-Methods present = %v, want %v", got, tt.wantMethods)
+			}
+			if got := h.Get("Access-Control-Expose-Headers") != ""; got != tt.wantExpose {
+				t.Errorf("Access-Control-Expose-Headers present = %v, want %v", got, tt.wantExpose)
+			}
+			if got := h.Get("Access-Control-Allow-Credentials") == "true"; got != tt.wantCreds {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want credentials %v", h.Get("Access-Control-Allow-Credentials"), tt.wantCreds)
+			}
+			if wantVary := tt.origin != ""; (h.Get("Vary") == "Origin") != wantVary {
+				t.Errorf("Vary = %q, want Origin: %v", h.Get("Vary"), wantVary)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"Hack4Change/apperrors"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DecompressBody accepts request bodies sent with Content-Encoding: gzip.
+// Put it after the route's MaxBodySize: the limit in force then applies to
+// the decompressed body as well as the compressed upload, so a small gzip
+// bomb cannot expand past it.
+func DecompressBody() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding"))) {
+		case "", "identity":
+			c.Next()
+			return
+		case "gzip", "x-gzip":
+		default:
+			_ = c.Error(apperrors.Validation("Unsupported request Content-Encoding").
+				WithField("Content-Encoding", "must be gzip or omitted"))
+			c.Abort()
+			return
+		}
+
+		gz, err := gzip.NewReader(c.Request.Body)
+		if err != nil {
+			_ = c.Error(apperrors.Wrap(apperrors.KindValidation, err, "Request body is not valid gzip"))
+			c.Abort()
+			return
+		}
+		var body io.ReadCloser = gzipBody{Reader: gz, compressed: c.Request.Body}
+		if limit := c.GetInt64(bodyLimitKey); limit > 0 {
+			body = http.MaxBytesReader(c.Writer, body, limit)
+		}
+
+		c.Request.Body = body
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Del("Content-Length")
+		c.Request.ContentLength = -1
+		c.Next()
+	}
+}
+
+type gzipBody struct {
+	*gzip.Reader
+	compressed io.ReadCloser
+}
+
+func (b gzipBody) Close() error {
+	b.Reader.Close()
+	return b.compressed.Close()
+}
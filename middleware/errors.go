@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"Hack4Change/apperrors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type errorBody struct {
+	Code      string            `json:"code"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	Details   any               `json:"details,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+}
+
+// ErrorHandler renders the last error recorded with c.Error as the standard
+// JSON envelope: {"error": {"code", "message", "fields", "details",
+// "request_id"}}.
+// Internal errors are logged and replaced with a generic message.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		appErr := apperrors.From(c.Errors.Last().Err)
+		if appErr.Kind == apperrors.KindInternal {
+			RequestLogger(c).Error("Unhandled error", "error", appErr.Err)
+		}
+
+		c.JSON(appErr.Kind.Status(), gin.H{"error": errorBody{
+			Code:      appErr.Kind.Code(),
+			Message:   appErr.Message,
+			Fields:    appErr.Fields,
+			Details:   appErr.Details,
+			RequestID: c.GetString(RequestIDKey),
+		}})
+	}
+}
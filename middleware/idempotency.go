@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"Hack4Change/apperrors"
+	"Hack4Change/database"
+	"Hack4Change/validation"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// IdempotencyStore persists Idempotency-Key reservations and the responses
+// recorded for them. *database.PostQreSQLCon implements it.
+type IdempotencyStore interface {
+	ReserveIdempotencyKey(ctx context.Context, userID, key, requestHash string, ttl time.Duration) (*database.IdempotencyRecord, error)
+	CompleteIdempotencyKey(ctx context.Context, userID, key string, statusCode int, contentType string, body []byte) error
+	ReleaseIdempotencyKey(ctx context.Context, userID, key string) error
+}
+
+// Idempotency makes a POST route safe to retry. The first request with a
+// given Idempotency-Key runs normally and its response is stored per user
+// for ttl; retries with the same key and body get that response replayed
+// with Idempotent-Replayed: true. Reusing a key for a different request, or
+// retrying while the first is still running, is a 409. Failed requests
+// (5xx, or errors rendered by ErrorHandler) release the key instead of
+// storing the failure. Must run after AuthMiddleware; requests without the
+// header are passed through untouched.
+func Idempotency(store IdempotencyStore, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		userID := c.GetString("userID")
+		if key == "" || userID == "" || c.Request.Method != http.MethodPost {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			_ = c.Error(apperrors.Validation("Invalid Idempotency-Key").
+				WithField(IdempotencyKeyHeader, "must be at most 255 characters"))
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			_ = c.Error(validation.Error(err))
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		// The query string is part of the request: import takes its
+		// options there.
+		hash := requestHash(c.Request.Method, c.Request.URL.RequestURI(), body)
+
+		existing, err := store.ReserveIdempotencyKey(c.Request.Context(), userID, key, hash, ttl)
+		if err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		if existing != nil {
+			switch {
+			case existing.RequestHash != hash:
+				_ = c.Error(apperrors.Conflict("Idempotency-Key was already used for a different request").
+					WithField(IdempotencyKeyHeader, "already used with a different request body"))
+				c.Abort()
+			case !existing.Completed:
+				c.Header("Retry-After", "1")
+				_ = c.Error(apperrors.Conflict("A request with this Idempotency-Key is still in progress"))
+				c.Abort()
+			default:
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Data(existing.StatusCode, existing.ContentType, existing.ResponseBody)
+				c.Abort()
+			}
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		c.Writer = recorder.ResponseWriter
+
+		// The client may have hung up, which is exactly when the outcome
+		// matters most; finish the bookkeeping regardless.
+		ctx := context.WithoutCancel(c.Request.Context())
+		status := recorder.Status()
+		if recorder.Written() && status < http.StatusInternalServerError {
+			err = store.CompleteIdempotencyKey(ctx, userID, key, status, recorder.Header().Get("Content-Type"), recorder.body.Bytes())
+		} else {
+			err = store.ReleaseIdempotencyKey(ctx, userID, key)
+		}
+		if err != nil {
+			RequestLogger(c).Warn("Failed to record idempotent response", "error", err)
+		}
+	}
+}
+
+func requestHash(method, uri string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + uri + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder keeps a copy of the response body as it is written.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"Hack4Change/apperrors"
+	"Hack4Change/database"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeIdempotencyStore keeps reservations in memory, the way the
+// idempotency_keys table does.
+type fakeIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*database.IdempotencyRecord
+}
+
+func newFakeIdempotencyStore() *fakeIdempotencyStore {
+	return &fakeIdempotencyStore{records: map[string]*database.IdempotencyRecord{}}
+}
+
+func (s *fakeIdempotencyStore) ReserveIdempotencyKey(_ context.Context, userID, key, requestHash string, _ time.Duration) (*database.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.records[userID+"/"+key]; ok {
+		record := *existing
+		return &record, nil
+	}
+	s.records[userID+"/"+key] = &database.IdempotencyRecord{UserID: userID, Key: key, RequestHash: requestHash}
+	return nil, nil
+}
+
+func (s *fakeIdempotencyStore) CompleteIdempotencyKey(_ context.Context, userID, key string, statusCode int, contentType string, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := s.records[userID+"/"+key]
+	record.StatusCode, record.ContentType, record.ResponseBody, record.Completed = statusCode, contentType, body, true
+	return nil
+}
+
+func (s *fakeIdempotencyStore) ReleaseIdempotencyKey(_ context.Context, userID, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, userID+"/"+key)
+	return nil
+}
+
+// idempotencyRouter mounts Idempotency in front of handler on POST /things,
+// with the user taken from X-User.
+func idempotencyRouter(store IdempotencyStore, handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler())
+	r.POST("/things",
+		func(c *gin.Context) { c.Set("userID", c.GetHeader("X-User")) },
+		Idempotency(store, time.Hour),
+		handler,
+	)
+	return r
+}
+
+func postThing(r http.Handler, user, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/things", strings.NewReader(body))
+	req.Header.Set("X-User", user)
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	var calls atomic.Int32
+	r := idempotencyRouter(newFakeIdempotencyStore(), func(c *gin.Context) {
+		n := calls.Add(1)
+		c.JSON(http.StatusCreated, gin.H{"call": n})
+	})
+
+	first := postThing(r, "u1", "k1", `{"name":"a"}`)
+	if first.Code != http.StatusCreated || first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Fatalf("first request: status %d, replayed %q", first.Code, first.Header().Get(IdempotentReplayedHeader))
+	}
+
+	retry := postThing(r, "u1", "k1", `{"name":"a"}`)
+	if retry.Code != http.StatusCreated {
+		t.Errorf("retry status = %d, want 201", retry.Code)
+	}
+	if retry.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("retry is missing %s: true", IdempotentReplayedHeader)
+	}
+	if retry.Body.String() != first.Body.String() {
+		t.Errorf("retry body = %s, want %s", retry.Body, first.Body)
+	}
+	if got := retry.Header().Get("Content-Type"); got != first.Header().Get("Content-Type") {
+		t.Errorf("retry Content-Type = %q, want %q", got, first.Header().Get("Content-Type"))
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("handler ran %d times, want 1", n)
+	}
+
+	// Keys belong to a user, and requests without a key are not tracked.
+	if w := postThing(r, "u2", "k1", `{"name":"a"}`); w.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Error("another user's request with the same key was replayed")
+	}
+	postThing(r, "u1", "", `{"name":"a"}`)
+	if n := calls.Load(); n != 3 {
+		t.Errorf("handler ran %d times, want 3", n)
+	}
+}
+
+func TestIdempotencyRejects(t *testing.T) {
+	r := idempotencyRouter(newFakeIdempotencyStore(), func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+	postThing(r, "u1", "k1", `{"name":"a"}`)
+
+	w := postThing(r, "u1", "k1", `{"name":"b"}`)
+	if w.Code != http.StatusConflict {
+		t.Errorf("same key, different body: status = %d, want 409", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), IdempotencyKeyHeader) {
+		t.Errorf("same key, different body: error does not name %s: %s", IdempotencyKeyHeader, w.Body)
+	}
+
+	w = postThing(r, "u1", strings.Repeat("k", maxIdempotencyKeyLength+1), `{}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("over-long key: status = %d, want 400", w.Code)
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	r := idempotencyRouter(newFakeIdempotencyStore(), func(c *gin.Context) {
+		close(started)
+		<-finish
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- postThing(r, "u1", "k1", `{}`) }()
+	<-started
+
+	w := postThing(r, "u1", "k1", `{}`)
+	if w.Code != http.StatusConflict {
+		t.Errorf("concurrent retry: status = %d, want 409", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("concurrent retry: Retry-After = %q, want 1", got)
+	}
+
+	close(finish)
+	if first := <-done; first.Code != http.StatusCreated {
+		t.Errorf("first request: status = %d, want 201", first.Code)
+	}
+	if w := postThing(r, "u1", "k1", `{}`); w.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("retry after completion was not replayed: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestIdempotencyReleasesFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		fail gin.HandlerFunc
+		want int
+	}{
+		{
+			name: "5xx response",
+			fail: func(c *gin.Context) { c.JSON(http.StatusServiceUnavailable, gin.H{"retry": true}) },
+			want: http.StatusServiceUnavailable,
+		},
+		{
+			name: "error for ErrorHandler",
+			fail: func(c *gin.Context) { _ = c.Error(apperrors.NotFound("folder not found")); c.Abort() },
+			want: http.StatusNotFound,
+		},
+		{
+			name: "handler that writes nothing",
+			fail: func(c *gin.Context) {},
+			want: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			r := idempotencyRouter(newFakeIdempotencyStore(), func(c *gin.Context) {
+				if calls.Add(1) == 1 {
+					tt.fail(c)
+					return
+				}
+				c.JSON(http.StatusCreated, gin.H{"ok": true})
+			})
+
+			if w := postThing(r, "u1", "k1", `{}`); w.Code != tt.want {
+				t.Fatalf("failing request: status = %d, want %d", w.Code, tt.want)
+			}
+			w := postThing(r, "u1", "k1", `{}`)
+			if w.Code != http.StatusCreated || w.Header().Get(IdempotentReplayedHeader) != "" {
+				t.Errorf("retry: status = %d, replayed %q; want a fresh 201", w.Code, w.Header().Get(IdempotentReplayedHeader))
+			}
+		})
+	}
+}
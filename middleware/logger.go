@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"Hack4Change/logging"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Logger attaches a request-scoped logger (request ID, method, route) to the
+// request context and writes one access log line when the request finishes.
+// It must run after RequestID.
+func Logger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		logger := slog.Default().With(
+			slog.String("request_id", c.GetString(RequestIDKey)),
+			slog.String("method", c.Request.Method),
+			slog.String("route", route),
+		)
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.IsValid() {
+			logger = logger.With(slog.String("trace_id", sc.TraceID().String()))
+		}
+		c.Request = c.Request.WithContext(logging.WithLogger(c.Request.Context(), logger))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if userID := c.GetString("userID"); userID != "" {
+			attrs = append(attrs, slog.String("user_id", userID))
+		}
+		logger.LogAttrs(c.Request.Context(), level, "Request completed", attrs...)
+	}
+}
+
+// RequestLogger returns the logger attached by Logger, or the default one.
+func RequestLogger(c *gin.Context) *slog.Logger {
+	return logging.FromContext(c.Request.Context())
+}
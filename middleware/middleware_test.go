@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"Hack4Change/apperrors"
+	"Hack4Change/models"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type fakeProjects map[string]models.ProjectDetails
+
+func (f fakeProjects) FetchProject(_ context.Context, projectID string) (models.ProjectDetails, error) {
+	switch projectID {
+	case "malformed":
+		return models.ProjectDetails{}, apperrors.Validation("malformed identifier")
+	case "broken":
+		return models.ProjectDetails{}, errors.New("connection refused")
+	}
+	project, ok := f[projectID]
+	if !ok {
+		return project, apperrors.NotFound("project not found")
+	}
+	return project, nil
+}
+
+func TestRequireProjectOwner(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	projects := fakeProjects{"p1": {ProjectID: "p1", OwnerID: "owner"}}
+	r := gin.New()
+	r.Use(ErrorHandler())
+	r.GET("/space/:id/tree",
+		func(c *gin.Context) { c.Set("userID", c.GetHeader("X-User")) },
+		RequireProjectOwner(projects),
+		func(c *gin.Context) { c.String(http.StatusOK, "tree") },
+	)
+
+	tests := []struct {
+		name      string
+		projectID string
+		user      string
+		want      int
+	}{
+		{name: "owner", projectID: "p1", user: "owner", want: http.StatusOK},
+		{name: "another user", projectID: "p1", user: "intruder", want: http.StatusNotFound},
+		{name: "no such space", projectID: "p2", user: "owner", want: http.StatusNotFound},
+		{name: "malformed space ID", projectID: "malformed", user: "owner", want: http.StatusNotFound},
+		{name: "lookup failure", projectID: "broken", user: "owner", want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/space/"+tt.projectID+"/tree", nil)
+			req.Header.Set("X-User", tt.user)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+
+	// A non-owner cannot tell someone else's space from a missing one.
+	body := func(projectID string) string {
+		req := httptest.NewRequest(http.MethodGet, "/space/"+projectID+"/tree", nil)
+		req.Header.Set("X-User", "intruder")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+	if got, missing := body("p1"), body("p2"); got != missing {
+		t.Errorf("non-owner got %s, but a missing space gives %s", got, missing)
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"Hack4Change/apperrors"
+	"Hack4Change/ratelimit"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit enforces limit per caller for the routes it is attached to.
+// Callers are identified by user ID when AuthMiddleware ran first, and by
+// client IP otherwise; name keeps each route group's buckets separate.
+// Responses carry RateLimit-* headers, and rejected requests get 429 with
+// Retry-After. If the store fails the request is let through.
+func RateLimit(store ratelimit.Store, name string, limit ratelimit.Limit) gin.HandlerFunc {
+	if !limit.Enabled() {
+		return func(c *gin.Context) { c.Next() }
+	}
+	policy := strconv.Itoa(limit.Burst) + ";w=" + strconv.Itoa(ceilSeconds(limit.Window()))
+
+	return func(c *gin.Context) {
+		key := name + ":ip:" + c.ClientIP()
+		if userID := c.GetString("userID"); userID != "" {
+			key = name + ":user:" + userID
+		}
+
+		res, err := store.Take(c.Request.Context(), key, limit)
+		if err != nil {
+			RequestLogger(c).Warn("Rate limit store unavailable, allowing request", "limiter", name, "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", policy)
+		c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		if !res.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			_ = c.Error(apperrors.RateLimited("Too many requests, slow down"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"Hack4Change/ratelimit"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type brokenStore struct{}
+
+func (brokenStore) Take(context.Context, string, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+func (brokenStore) Cleanup(context.Context, time.Duration) error { return nil }
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(store ratelimit.Store) *gin.Engine {
+		r := gin.New()
+		r.Use(ErrorHandler())
+		r.GET("/x", RateLimit(store, "test", ratelimit.Limit{Rate: 1.0 / 60, Burst: 2}), func(c *gin.Context) {
+			c.String(http.StatusOK, "ok")
+		})
+		return r
+	}
+	get := func(r *gin.Engine) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/x", nil))
+		return w
+	}
+
+	r := newRouter(ratelimit.NewMemoryStore())
+	for i, wantRemaining := range []string{"1", "0"} {
+		w := get(r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i, w.Code)
+		}
+		if got := w.Header().Get("RateLimit-Remaining"); got != wantRemaining {
+			t.Errorf("request %d: RateLimit-Remaining = %q, want %q", i, got, wantRemaining)
+		}
+		if got := w.Header().Get("RateLimit-Policy"); got != "2;w=120" {
+			t.Errorf("request %d: RateLimit-Policy = %q, want 2;w=120", i, got)
+		}
+	}
+	w := get(r)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", w.Code)
+	}
+	// One token takes a minute to come back; the header rounds up.
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q, want 60", got)
+	}
+
+	// A failing store lets requests through without limit headers.
+	w = get(newRouter(brokenStore{}))
+	if w.Code != http.StatusOK {
+		t.Errorf("with a broken store: status = %d, want 200", w.Code)
+	}
+	if got := w.Header().Get("RateLimit-Limit"); got != "" {
+		t.Errorf("with a broken store: RateLimit-Limit = %q, want none", got)
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"Hack4Change/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	RequestIDKey    = "requestID"
+)
+
+// RequestID assigns every request an ID, reusing the caller's X-Request-ID
+// when it looks sane, and echoes it back in the response headers.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+		c.Set(RequestIDKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), requestID))
+		c.Next()
+	}
+}
+
+// validRequestID accepts short printable IDs so callers can't inject
+// arbitrary data into our logs and headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
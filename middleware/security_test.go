@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(hstsMaxAge time.Duration) *gin.Engine {
+		r := gin.New()
+		r.Use(SecurityHeaders(hstsMaxAge))
+		r.GET("/docs", ContentSecurityPolicy(DocsContentSecurityPolicy), func(c *gin.Context) {
+			c.Data(http.StatusOK, "text/html; charset=utf-8", []byte("<html></html>"))
+		})
+		r.GET("/v1/space/details", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{}) })
+		return r
+	}
+	hsts := newRouter(time.Hour)
+	noHSTS := newRouter(0)
+
+	tests := []struct {
+		name     string
+		router   *gin.Engine
+		path     string
+		https    bool
+		proto    string
+		wantCSP  string
+		wantHSTS string
+	}{
+		{name: "API", router: hsts, path: "/v1/space/details", wantCSP: APIContentSecurityPolicy},
+		{name: "docs", router: hsts, path: "/docs", wantCSP: DocsContentSecurityPolicy},
+		{name: "unknown route", router: hsts, path: "/nope", wantCSP: APIContentSecurityPolicy},
+		{name: "HTTPS", router: hsts, path: "/v1/space/details", https: true, wantCSP: APIContentSecurityPolicy, wantHSTS: "max-age=3600; includeSubDomains"},
+		{name: "HTTPS behind a proxy", router: hsts, path: "/docs", proto: "https", wantCSP: DocsContentSecurityPolicy, wantHSTS: "max-age=3600; includeSubDomains"},
+		{name: "HTTP behind a proxy", router: hsts, path: "/v1/space/details", proto: "http", wantCSP: APIContentSecurityPolicy},
+		{name: "HSTS disabled", router: noHSTS, path: "/v1/space/details", https: true, wantCSP: APIContentSecurityPolicy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.https {
+				req.TLS = &tls.ConnectionState{}
+			}
+			if tt.proto != "" {
+				req.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			w := httptest.NewRecorder()
+			tt.router.ServeHTTP(w, req)
+
+			h := w.Header()
+			if got := h.Get("Content-Security-Policy"); got != tt.wantCSP {
+				t.Errorf("Content-Security-Policy = %q, want %q", got, tt.wantCSP)
+			}
+			if got := h.Get("Strict-Transport-Security"); got != tt.wantHSTS {
+				t.Errorf("Strict-Transport-Security = %q, want %q", got, tt.wantHSTS)
+			}
+			for name, want := range map[string]string{
+				"X-Content-Type-Options": "nosniff",
+				"X-Frame-Options":        "DENY",
+				"Referrer-Policy":        "no-referrer",
+			} {
+				if got := h.Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const APIVersionKey = "apiVersion"
+
+// APIVersion tags requests with the API version of the group they were
+// routed through, so handlers can pick the matching response envelope.
+func APIVersion(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(APIVersionKey, version)
+		c.Next()
+	}
+}
+
+// Deprecated marks every response of a route group as deprecated (RFC 9745)
+// with a Sunset date (RFC 8594) and a Link to the same path under
+// successorPrefix.
+func Deprecated(successorPrefix string, deprecatedAt, sunset time.Time) gin.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", deprecatedAt.Unix())
+	sunsetHeader := sunset.UTC().Format(http.TimeFormat)
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		c.Header("Sunset", sunsetHeader)
+		successor := successorPrefix + "/" + strings.TrimPrefix(c.Request.URL.Path, "/")
+		c.Header("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+		c.Next()
+	}
+}
@@ -25,17 +25,23 @@ type CreateAccountReq struct {
 }
 
 type Login struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
 }
 
 type Socials struct {
-	GitHub      string `json:"github" validate:"omitempty,url"`
-	LinkedIn    string `json:"linkedin" validate:"omitempty,url"`
-	Instagram   string `json:"instagram" validate:"omitempty,url"`
+	GitHub      string `json:"github" validate:"omitempty,url,social_url=github"`
+	LinkedIn    string `json:"linkedin" validate:"omitempty,url,social_url=linkedin"`
+	Instagram   string `json:"instagram" validate:"omitempty,url,social_url=instagram"`
 	NoobsSocial string `json:"noobs_social" validate:"omitempty,url"`
 }
 
+// User roles stored in users.role.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type UserDetails struct {
 	ID             string    `json:"id"`
 	Username       string    `json:"username"`
@@ -44,6 +50,7 @@ type UserDetails struct {
 	FirstName      string    `json:"first_name"`
 	LastName       string    `json:"last_name"`
 	SocialAccounts Socials   `json:"social_accounts"`
+	Role           string    `json:"role"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
 	Badges         []Badge   `json:"badges"`
@@ -67,8 +74,9 @@ type File struct {
 	ID             string    `json:"id"`
 	ProjectID      string    `json:"project_id"`
 	ParentFolderId *string   `json:"parent_folder_id"`
-	FileName       string    `json:"file_name" validate:"required,min=1,max=255"`
+	FileName       string    `json:"file_name" validate:"required,min=1,max=255,safe_filename"`
 	FileContent    string    `json:"file_content" validate:"required"`
+	Version        int       `json:"version"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
 }
@@ -76,7 +84,7 @@ type File struct {
 type Folder struct {
 	ID             string  `json:"id"`
 	ProjectID      string  `json:"project_id"`
-	FolderName     string  `json:"folder_name" validate:"required,min=1,max=255"`
+	FolderName     string  `json:"folder_name" validate:"required,min=1,max=255,safe_filename"`
 	ParentFolderId *string `json:"parent_folder_id"`
 
 	CreatedAt time.Time `json:"created_at"`
@@ -96,16 +104,16 @@ type CreateAccountDetails struct {
 }
 
 type CreateFileReq struct {
-	ProjectID      string  `json:"project_id" validate:"required"`
-	FileName       string  `json:"file_name" validate:"required,min=1,max=255"`
+	ProjectID      string  `json:"project_id" validate:"required,uuid"`
+	FileName       string  `json:"file_name" validate:"required,min=1,max=255,safe_filename"`
 	FileContent    string  `json:"file_content" validate:"required"`
-	ParentFolderId *string `json:"parent_folder_id"`
+	ParentFolderId *string `json:"parent_folder_id" validate:"omitempty,uuid"`
 }
 
 type CreateFolderReq struct {
-	ProjectID      string  `json:"project_id" validate:"required"`
-	FolderName     string  `json:"folder_name" validate:"required,min=1,max=255"`
-	ParentFolderId *string `json:"parent_folder_id"`
+	ProjectID      string  `json:"project_id" validate:"required,uuid"`
+	FolderName     string  `json:"folder_name" validate:"required,min=1,max=255,safe_filename"`
+	ParentFolderId *string `json:"parent_folder_id" validate:"omitempty,uuid"`
 }
 
 type FolderDetails struct {
@@ -117,10 +125,20 @@ type FolderDetails struct {
 	UpdatedAt      time.Time `json:"updated_at"`
 	Files          []File    `json:"files"`
 }
+
+// SaveFileRequest carries the version the client edited in
+// ExpectedVersion, unless it sends an If-Match header instead.
 type SaveFileRequest struct {
-	ProjectID uuid.UUID `json:"projectId"`
-	FileID    uuid.UUID `json:"fileId"`
-	Content   string    `json:"content"`
+	FileID          uuid.UUID `json:"file_id" validate:"required"`
+	Content         string    `json:"content"`
+	ExpectedVersion *int      `json:"expected_version" validate:"omitempty,min=1"`
+}
+
+type SaveFileRes struct {
+	Message   string    `json:"message"`
+	FileID    string    `json:"file_id"`
+	Version   int       `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type Badge struct {
@@ -160,18 +178,296 @@ type SkillRes struct {
 	Data  []SkillData `json:"data"`
 }
 type StatusReq struct {
-	SkillId string `json:"skill_id"`
+	SkillId string `json:"skill_id" validate:"required,uuid"`
 }
 
 type GenerateSkillsReq struct {
-	Difficulty string `json:"difficulty"`
-	Topic      string `json:"topic"`
+	Difficulty string `json:"difficulty" validate:"required,max=32"`
+	Topic      string `json:"topic" validate:"required,max=255"`
 }
 type SubmitSolReq struct {
-	Code string `json:"code"`
+	Code string `json:"code" validate:"required"`
 }
 
 type ProjectContents struct {
 	Folders []FolderDetails `json:"folders"`
 	Files   []File          `json:"files"`
 }
+
+type ResetDataReq struct {
+	Entities          []string `json:"entities" validate:"required,min=1,dive,required"`
+	DryRun            bool     `json:"dry_run"`
+	ConfirmationToken string   `json:"confirmation_token" validate:"required_without=DryRun"`
+}
+
+type ResetDataRes struct {
+	DryRun            bool             `json:"dry_run"`
+	Tables            []string         `json:"tables"`
+	Rows              map[string]int64 `json:"rows"`
+	ConfirmationToken string           `json:"confirmation_token,omitempty"`
+	ExpiresAt         *time.Time       `json:"expires_at,omitempty"`
+}
+
+// FileRevision describes one saved version of a file. AuthorID is nil for
+// history that predates revision tracking or whose author was deleted.
+type FileRevision struct {
+	FileID       string    `json:"file_id" db:"file_id"`
+	Version      int       `json:"version" db:"version"`
+	AuthorID     *string   `json:"author_id" db:"author_id"`
+	ContentHash  string    `json:"content_hash" db:"content_hash"`
+	SizeBytes    int       `json:"size_bytes" db:"size_bytes"`
+	RestoredFrom *int      `json:"restored_from,omitempty" db:"restored_from"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+type FileRevisionContent struct {
+	FileRevision
+	Content string `json:"content" db:"content"`
+}
+
+// ProjectRevision is a FileRevisionContent with its author's name, as
+// replayed when a space's history is exported. The author fields are empty
+// when AuthorID is nil.
+type ProjectRevision struct {
+	FileRevisionContent
+	AuthorName  string `json:"author_name" db:"author_name"`
+	AuthorEmail string `json:"author_email" db:"author_email"`
+}
+
+type FileDiffRes struct {
+	FileID  string `json:"file_id"`
+	From    int    `json:"from"`
+	To      int    `json:"to"`
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+	Diff    string `json:"diff"`
+}
+
+type RestoreRevisionReq struct {
+	ExpectedVersion *int `json:"expected_version" validate:"omitempty,min=1"`
+}
+
+type RenameReq struct {
+	Name string `json:"name" validate:"required,min=1,max=255,safe_filename"`
+}
+
+// MoveReq names the destination folder; a null or missing parent_folder_id
+// moves to the root of the space.
+type MoveReq struct {
+	ParentFolderId *string `json:"parent_folder_id" validate:"omitempty,uuid"`
+}
+
+type DeleteFolderRes struct {
+	Message        string `json:"message"`
+	DeletedFolders int64  `json:"deleted_folders"`
+	DeletedFiles   int64  `json:"deleted_files"`
+}
+
+// TreeFolder is a folder with everything beneath it.
+type TreeFolder struct {
+	ID             string       `json:"id"`
+	ProjectID      string       `json:"project_id"`
+	FolderName     string       `json:"folder_name"`
+	ParentFolderId *string      `json:"parent_folder_id"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+	Folders        []TreeFolder `json:"folders"`
+	Files          []File       `json:"files"`
+}
+
+// ProjectTree is a whole space as nested folders. When ContentIncluded is
+// false every file_content is left empty.
+type ProjectTree struct {
+	ProjectID       string       `json:"project_id"`
+	ContentIncluded bool         `json:"content_included"`
+	Folders         []TreeFolder `json:"folders"`
+	Files           []File       `json:"files"`
+}
+
+// FileMeta is what a file browser needs to show a file without its content.
+type FileMeta struct {
+	ID             string    `json:"id"`
+	ParentFolderId *string   `json:"parent_folder_id"`
+	FileName       string    `json:"file_name"`
+	SizeBytes      int64     `json:"size_bytes"`
+	Language       string    `json:"language"`
+	Version        int       `json:"version"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// FolderMeta describes a folder; ChildCount counts its direct subfolders
+// and files.
+type FolderMeta struct {
+	ID             string    `json:"id"`
+	ParentFolderId *string   `json:"parent_folder_id"`
+	FolderName     string    `json:"folder_name"`
+	ChildCount     int       `json:"child_count"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type MetaTreeFolder struct {
+	FolderMeta
+	Folders []MetaTreeFolder `json:"folders"`
+	Files   []FileMeta       `json:"files"`
+}
+
+type MetaTree struct {
+	ProjectID string           `json:"project_id"`
+	Folders   []MetaTreeFolder `json:"folders"`
+	Files     []FileMeta       `json:"files"`
+}
+
+// FolderChildren lists one folder's direct contents; FolderID is nil for
+// the root of the space.
+type FolderChildren struct {
+	FolderID *string      `json:"folder_id"`
+	Folders  []FolderMeta `json:"folders"`
+	Files    []FileMeta   `json:"files"`
+}
+
+// ImportSpaceReq carries the import options, sent as query parameters
+// since the body is the archive itself.
+type ImportSpaceReq struct {
+	ProjectName        string `form:"name" json:"name" validate:"required,min=3,max=50"`
+	ProjectDescription string `form:"description" json:"description" validate:"omitempty,max=255"`
+	Format             string `form:"format" json:"format" validate:"omitempty,oneof=zip tar.gz"`
+}
+
+// SkippedEntry is an archive entry that was left out of an import.
+type SkippedEntry struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+type ImportSpaceRes struct {
+	ProjectID string         `json:"project_id"`
+	Folders   int            `json:"folders"`
+	Files     int            `json:"files"`
+	Skipped   []SkippedEntry `json:"skipped"`
+}
+
+// PathFile is a file with its slash-separated path from the space root.
+type PathFile struct {
+	File
+	Path string `json:"path"`
+}
+
+// SearchReq is a code search, sent as query parameters. Regex patterns use
+// RE2 syntax and, like plain queries, match within a single line.
+type SearchReq struct {
+	Query      string `form:"q" json:"q" validate:"required,max=1000"`
+	Regex      bool   `form:"regex" json:"regex"`
+	IgnoreCase bool   `form:"ignore_case" json:"ignore_case"`
+	WholeWord  bool   `form:"whole_word" json:"whole_word"`
+	Context    int    `form:"context,default=2" json:"context" validate:"min=0,max=10"`
+	Limit      int    `form:"limit,default=200" json:"limit" validate:"min=1,max=1000"`
+}
+
+// SearchRange is one match within a line, as character offsets with End
+// exclusive.
+type SearchRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// SearchLine is a matching line with the lines around it.
+type SearchLine struct {
+	Line   int           `json:"line"`
+	Text   string        `json:"text"`
+	Ranges []SearchRange `json:"ranges"`
+	Before []string      `json:"before"`
+	After  []string      `json:"after"`
+}
+
+type SearchFileResult struct {
+	FileID  string       `json:"file_id"`
+	Path    string       `json:"path"`
+	Version int          `json:"version"`
+	Lines   []SearchLine `json:"lines"`
+}
+
+// SearchRes lists matching lines grouped by file, in path order. Matches
+// counts lines; Truncated is set when the limit cut further matches off.
+type SearchRes struct {
+	Files     []SearchFileResult `json:"files"`
+	Matches   int                `json:"matches"`
+	Truncated bool               `json:"truncated"`
+}
+
+// ReplaceQuery is the search and replacement shared by a find-and-replace
+// preview and its apply. For regex queries $1 or ${name} in Replacement
+// expand to capture groups.
+type ReplaceQuery struct {
+	Query       string `json:"q" validate:"required,max=1000"`
+	Regex       bool   `json:"regex"`
+	IgnoreCase  bool   `json:"ignore_case"`
+	WholeWord   bool   `json:"whole_word"`
+	Replacement string `json:"replacement" validate:"max=1000"`
+}
+
+type ReplacePreviewReq struct {
+	ReplaceQuery
+	Limit int `json:"limit" validate:"omitempty,min=1,max=500"`
+}
+
+// ReplaceFilePreview is the change a replacement would make to one file at
+// Version.
+type ReplaceFilePreview struct {
+	FileID       string `json:"file_id"`
+	Path         string `json:"path"`
+	Version      int    `json:"version"`
+	Replacements int    `json:"replacements"`
+	Added        int    `json:"added"`
+	Removed      int    `json:"removed"`
+	Diff         string `json:"diff"`
+}
+
+// ReplacePreviewRes lists the files a replacement would change, in path
+// order. Truncated is set when more files match than the limit allowed, or
+// than one preview diffs.
+type ReplacePreviewRes struct {
+	Files        []ReplaceFilePreview `json:"files"`
+	Replacements int                  `json:"replacements"`
+	Truncated    bool                 `json:"truncated"`
+}
+
+// ReplaceFileVersion accepts the previewed change to a file, which is only
+// applied while the file is still at Version.
+type ReplaceFileVersion struct {
+	FileID  string `json:"file_id" validate:"required,uuid"`
+	Version int    `json:"version" validate:"required,min=1"`
+}
+
+type ReplaceApplyReq struct {
+	ReplaceQuery
+	Files []ReplaceFileVersion `json:"files" validate:"required,min=1,max=500,unique=FileID,dive"`
+}
+
+// FileEdit is new content for a file that must still be at
+// ExpectedVersion.
+type FileEdit struct {
+	FileID          string
+	ExpectedVersion int
+	Content         string
+}
+
+// StaleFile is a file that changed after the preview, which rejects the
+// whole apply. CurrentVersion is nil when the file was deleted or no longer
+// matches.
+type StaleFile struct {
+	FileID          string `json:"file_id"`
+	ExpectedVersion int    `json:"expected_version"`
+	CurrentVersion  *int   `json:"current_version"`
+}
+
+type ReplacedFile struct {
+	FileID       string `json:"file_id"`
+	Path         string `json:"path"`
+	Version      int    `json:"version"`
+	Replacements int    `json:"replacements"`
+}
+
+type ReplaceApplyRes struct {
+	Files        []ReplacedFile `json:"files"`
+	Replacements int            `json:"replacements"`
+}
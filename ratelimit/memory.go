@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// MemoryStore keeps buckets in process memory. Limits are per instance, so
+// use PostgresStore when running more than one replica.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}, now: time.Now}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+	tokens, res := refill(b.tokens, now.Sub(b.updated), limit)
+	b.tokens, b.updated = tokens, now
+	return res, nil
+}
+
+func (s *MemoryStore) Cleanup(_ context.Context, idle time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := s.now().Add(-idle)
+	for key, b := range s.buckets {
+		if b.updated.Before(cutoff) {
+			delete(s.buckets, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"Hack4Change/database"
+)
+
+// PostgresStore keeps buckets in the rate_limit_buckets table so every
+// instance shares the same limits.
+type PostgresStore struct {
+	db *database.PostQreSQLCon
+}
+
+func NewPostgresStore(db *database.PostQreSQLCon) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	var res Result
+	err := s.db.UpdateRateLimitBucket(ctx, key, float64(limit.Burst), func(tokens float64, elapsed time.Duration) float64 {
+		tokens, res = refill(tokens, elapsed, limit)
+		return tokens
+	})
+	return res, err
+}
+
+func (s *PostgresStore) Cleanup(ctx context.Context, idle time.Duration) error {
+	return s.db.DeleteIdleRateLimitBuckets(ctx, idle)
+}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket: Burst tokens, refilled at Rate tokens per second.
+// The zero Limit disables limiting.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) Enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// Window is the time an empty bucket takes to refill completely.
+func (l Limit) Window() time.Duration {
+	if !l.Enabled() {
+		return 0
+	}
+	return time.Duration(float64(l.Burst) / l.Rate * float64(time.Second))
+}
+
+// ParseLimit reads limits written as "<requests>/<period>", e.g. "10/1m" or
+// "300/1h". "off" (or an empty string) disables the limit.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "off" {
+		return Limit{}, nil
+	}
+	count, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("ratelimit: %q is not <requests>/<period>", s)
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: invalid request count in %q", s)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: invalid period in %q", s)
+	}
+	return Limit{Rate: float64(n) / d.Seconds(), Burst: n}, nil
+}
+
+// Result describes the bucket after a Take.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request would be allowed; zero
+	// when Allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps token buckets. Implementations must make Take atomic per key.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+	// Cleanup forgets buckets idle for longer than idle; such buckets are
+	// full anyway.
+	Cleanup(ctx context.Context, idle time.Duration) error
+}
+
+// refill applies the token bucket arithmetic shared by every store: top up
+// tokens for the elapsed time, then try to spend one.
+func refill(tokens float64, elapsed time.Duration, limit Limit) (float64, Result) {
+	tokens = math.Min(float64(limit.Burst), tokens+elapsed.Seconds()*limit.Rate)
+	res := Result{Limit: limit.Burst}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+	res.Remaining = int(math.Floor(tokens))
+	res.Reset = seconds((float64(limit.Burst) - tokens) / limit.Rate)
+	return tokens, res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,157 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{in: "", want: Limit{}},
+		{in: "off", want: Limit{}},
+		{in: " 10/1m ", want: Limit{Rate: 10.0 / 60, Burst: 10}},
+		{in: "300/1h", want: Limit{Rate: 300.0 / 3600, Burst: 300}},
+		{in: "5/500ms", want: Limit{Rate: 10, Burst: 5}},
+		{in: "10", wantErr: true},
+		{in: "ten/1m", wantErr: true},
+		{in: "0/1m", wantErr: true},
+		{in: "-3/1m", wantErr: true},
+		{in: "10/minute", wantErr: true},
+		{in: "10/0s", wantErr: true},
+		{in: "10/-1m", wantErr: true},
+		{in: "/1m", wantErr: true},
+		{in: "10/", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseLimit(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseLimit(%q) = %+v, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseLimit(%q) error = %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimit(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitWindow(t *testing.T) {
+	if got := (Limit{Rate: 0.5, Burst: 10}).Window(); got != 20*time.Second {
+		t.Errorf("Window() = %v, want 20s", got)
+	}
+	if got := (Limit{}).Window(); got != 0 {
+		t.Errorf("disabled Window() = %v, want 0", got)
+	}
+}
+
+func TestRefill(t *testing.T) {
+	limit := Limit{Rate: 2, Burst: 4}
+	tests := []struct {
+		name       string
+		tokens     float64
+		elapsed    time.Duration
+		wantTokens float64
+		want       Result
+	}{
+		{
+			name: "full bucket", tokens: 4,
+			wantTokens: 3,
+			want:       Result{Allowed: true, Limit: 4, Remaining: 3, Reset: 500 * time.Millisecond},
+		},
+		{
+			name: "last token", tokens: 1,
+			wantTokens: 0,
+			want:       Result{Allowed: true, Limit: 4, Remaining: 0, Reset: 2 * time.Second},
+		},
+		{
+			name: "exhausted", tokens: 0,
+			wantTokens: 0,
+			want:       Result{Limit: 4, RetryAfter: 500 * time.Millisecond, Reset: 2 * time.Second},
+		},
+		{
+			name: "partly refilled", tokens: 0, elapsed: 250 * time.Millisecond,
+			wantTokens: 0.5,
+			want:       Result{Limit: 4, RetryAfter: 250 * time.Millisecond, Reset: 1750 * time.Millisecond},
+		},
+		{
+			name: "refilled enough", tokens: 0, elapsed: 500 * time.Millisecond,
+			wantTokens: 0,
+			want:       Result{Allowed: true, Limit: 4, Remaining: 0, Reset: 2 * time.Second},
+		},
+		{
+			name: "capped at burst", tokens: 1, elapsed: time.Hour,
+			wantTokens: 3,
+			want:       Result{Allowed: true, Limit: 4, Remaining: 3, Reset: 500 * time.Millisecond},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, res := refill(tt.tokens, tt.elapsed, limit)
+			if tokens != tt.wantTokens {
+				t.Errorf("tokens = %v, want %v", tokens, tt.wantTokens)
+			}
+			if res != tt.want {
+				t.Errorf("result = %+v, want %+v", res, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Rate: 1, Burst: 2}
+
+	steps := []struct {
+		advance    time.Duration
+		key        string
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+	}{
+		{key: "a", allowed: true, remaining: 1},
+		{key: "a", allowed: true, remaining: 0},
+		{key: "a", allowed: false, retryAfter: time.Second},
+		// Other keys have their own bucket.
+		{key: "b", allowed: true, remaining: 1},
+		{advance: 500 * time.Millisecond, key: "a", allowed: false, retryAfter: 500 * time.Millisecond},
+		{advance: 500 * time.Millisecond, key: "a", allowed: true, remaining: 0},
+		// A long pause refills no further than the burst.
+		{advance: time.Hour, key: "a", allowed: true, remaining: 1},
+	}
+	for i, step := range steps {
+		now = now.Add(step.advance)
+		res, err := store.Take(ctx, step.key, limit)
+		if err != nil {
+			t.Fatalf("step %d: Take() error = %v", i, err)
+		}
+		if res.Allowed != step.allowed || res.Remaining != step.remaining || res.RetryAfter != step.retryAfter {
+			t.Errorf("step %d: Take(%q) = %+v, want allowed=%v remaining=%d retryAfter=%v",
+				i, step.key, res, step.allowed, step.remaining, step.retryAfter)
+		}
+	}
+
+	// Cleanup forgets "b", idle for an hour, but keeps "a", just used.
+	if err := store.Cleanup(ctx, time.Minute); err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	if _, ok := store.buckets["b"]; ok {
+		t.Error("idle bucket b survived Cleanup")
+	}
+	if _, ok := store.buckets["a"]; !ok {
+		t.Error("active bucket a was removed by Cleanup")
+	}
+}
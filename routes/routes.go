@@ -1,25 +1,112 @@
 package routes
 
 import (
+	"Hack4Change/ai"
+	"Hack4Change/config"
 	"Hack4Change/database"
+	"Hack4Change/docs"
 	"Hack4Change/handlers"
+	"Hack4Change/metrics"
 	"Hack4Change/middleware"
+	"Hack4Change/models"
+	"Hack4Change/ratelimit"
+	"Hack4Change/spacearchive"
 	"net/http"
+	"sync/atomic"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-func InitializeRoutes(router *gin.Engine, dbConn *database.PostQreSQLCon) {
+// Dependencies are the shared services the routes are wired to.
+type Dependencies struct {
+	DB     *database.PostQreSQLCon
+	AI     *ai.Client
+	Config config.Config
+	// Draining is set once shutdown begins so /readyz starts failing.
+	Draining *atomic.Bool
+
+	RateLimiter ratelimit.Store
+	RateLimits  RateLimits
+}
+
+// RateLimits are the limits applied per route group. Auth routes are keyed
+// by client IP, everything behind AuthMiddleware by user ID.
+type RateLimits struct {
+	Auth       ratelimit.Limit
+	API        ratelimit.Limit
+	AIGenerate ratelimit.Limit
+}
+
+func InitializeRoutes(router *gin.Engine, deps Dependencies) {
+	dbConn := deps.DB
+	aiClient := deps.AI
+
+	cfg := deps.Config
+	router.Use(otelgin.Middleware(cfg.ServiceName), middleware.RequestID(), middleware.Logger(), middleware.Metrics(), middleware.ErrorHandler())
+	router.Use(
+		middleware.SecurityHeaders(cfg.HSTSMaxAge),
+		middleware.CORS(middleware.CORSOptions{
+			AllowedOrigins:   cfg.CORSAllowedOrigins,
+			AllowCredentials: cfg.CORSAllowCredentials,
+			MaxAge:           cfg.CORSMaxAge,
+		}),
+		middleware.Compress(middleware.CompressOptions{
+			MinSize:      cfg.CompressMinBytes,
+			ContentTypes: cfg.CompressContentTypes,
+		}),
+	)
+
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	router.GET("/openapi.yaml", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/yaml", docs.OpenAPI)
+	})
+	router.GET("/docs", middleware.ContentSecurityPolicy(middleware.DocsContentSecurityPolicy), func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", docs.UI)
+	})
+	router.GET("/healthz", handlers.Healthz)
+	router.GET("/readyz", func(c *gin.Context) {
+		handlers.Readyz(c, dbConn, aiClient, deps.Draining)
+	})
+
+	// The API is mounted once per version. Versions share handlers and
+	// storage; a new version changes response shapes by registering its
+	// envelope with handlers.RegisterEnvelope and mounting its own group:
+	//
+	//	registerAPI(router.Group("/v2", middleware.APIVersion("v2")), deps)
+	registerAPI(router.Group("/v1", middleware.APIVersion("v1")), deps)
+
+	// Legacy unversioned paths alias v1 until the sunset date.
+	registerAPI(router.Group("",
+		middleware.APIVersion("v1"),
+		middleware.Deprecated("/v1", cfg.LegacyDeprecatedAt, cfg.LegacySunset),
+	), deps)
+}
+
+func registerAPI(router *gin.RouterGroup, deps Dependencies) {
+	dbConn := deps.DB
+	aiClient := deps.AI
+	apiLimit := middleware.RateLimit(deps.RateLimiter, "api", deps.RateLimits.API)
+	aiLimit := middleware.RateLimit(deps.RateLimiter, "ai_generate", deps.RateLimits.AIGenerate)
+	fileBody := middleware.MaxBodySize(deps.Config.MaxFileBodyBytes)
+	importBody := middleware.MaxBodySize(deps.Config.ImportMaxBytes)
+	importLimits := spacearchive.Limits{
+		MaxEntries:    deps.Config.ImportMaxEntries,
+		MaxTotalBytes: deps.Config.ImportMaxTotalBytes,
+		MaxFileBytes:  deps.Config.MaxFileBodyBytes,
+	}
+	idempotent := middleware.Idempotency(dbConn, deps.Config.IdempotencyKeyTTL)
+
+	router.Use(middleware.MaxBodySize(deps.Config.MaxBodyBytes))
+
 	// Tested
 	router.GET("/test", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "success"})
 	})
-	router.GET("/create-tables", func(c *gin.Context) {
-		handlers.CreateTables(c, dbConn)
-	})
 	// Auth APIs
 	//Tested
 	authGroup := router.Group("/auth")
+	authGroup.Use(middleware.RateLimit(deps.RateLimiter, "auth", deps.RateLimits.Auth))
 	{
 		authGroup.POST("/register", func(c *gin.Context) {
 			handlers.Register(c, dbConn)
@@ -31,41 +118,121 @@ func InitializeRoutes(router *gin.Engine, dbConn *database.PostQreSQLCon) {
 
 	// Space APIs
 	spaceGroup := router.Group("/space")
-	spaceGroup.Use(middleware.AuthMiddleware())
+	spaceGroup.Use(middleware.AuthMiddleware(), apiLimit)
+	{
+		//Tested
+		spaceGroup.POST("/create-space", idempotent, func(c *gin.Context) {
+			handlers.CreateProject(c, dbConn)
+		})
+		spaceGroup.POST("/import", importBody, middleware.DecompressBody(), idempotent, func(c *gin.Context) {
+			handlers.ImportSpace(c, dbConn, importLimits)
+		})
+		//Tested
+		spaceGroup.GET("/details", func(c *gin.Context) {
+			handlers.FetchProjectsByUserId(c, dbConn)
+
+		})
+	}
+
+	// Everything under a space's ID is for its owner only.
+	projectGroup := spaceGroup.Group("/:id", middleware.RequireProjectOwner(dbConn))
 	{
 		//Tested
-		spaceGroup.GET("/:id/get-files", func(c *gin.Context) {
+		projectGroup.GET("/get-files", func(c *gin.Context) {
 			handlers.FetchFilesByProjectId(c, dbConn)
 		})
-		spaceGroup.GET("/:id/details", func(c *gin.Context) {
+		projectGroup.GET("/details", func(c *gin.Context) {
 			handlers.FetchFilesAndFoldersByProjectId(c, dbConn)
 		})
-		spaceGroup.POST("/:id/create-folder", func(c *gin.Context) {
+		projectGroup.POST("/create-folder", idempotent, func(c *gin.Context) {
 			handlers.CreateFolder(c, dbConn)
 		})
 
-		spaceGroup.POST("/:id/create-file", func(c *gin.Context) {
+		projectGroup.POST("/create-file", fileBody, idempotent, func(c *gin.Context) {
 			handlers.CreateFile(c, dbConn)
 		})
 
-		spaceGroup.POST("/:id/save-file", func(c *gin.Context) {
+		projectGroup.POST("/save-file", fileBody, middleware.DecompressBody(), func(c *gin.Context) {
 			handlers.SaveFileContent(c, dbConn)
 		})
-		//Tested
-		spaceGroup.POST("/create-space", func(c *gin.Context) {
-			handlers.CreateProject(c, dbConn)
+		projectGroup.POST("/files/:fileId/rename", func(c *gin.Context) {
+			handlers.RenameFile(c, dbConn)
 		})
-		//Tested
-		spaceGroup.GET("/details", func(c *gin.Context) {
-			handlers.FetchProjectsByUserId(c, dbConn)
-
+		projectGroup.POST("/files/:fileId/move", func(c *gin.Context) {
+			handlers.MoveFile(c, dbConn)
+		})
+		projectGroup.DELETE("/files/:fileId", func(c *gin.Context) {
+			handlers.DeleteFile(c, dbConn)
+		})
+		projectGroup.POST("/folders/:folderId/rename", func(c *gin.Context) {
+			handlers.RenameFolder(c, dbConn)
+		})
+		projectGroup.POST("/folders/:folderId/move", func(c *gin.Context) {
+			handlers.MoveFolder(c, dbConn)
+		})
+		projectGroup.DELETE("/folders/:folderId", func(c *gin.Context) {
+			handlers.DeleteFolder(c, dbConn)
 		})
-		spaceGroup.GET("/:id/structure", func(c *gin.Context) {
+		projectGroup.GET("/fs/*path", func(c *gin.Context) {
+			handlers.GetFileByPath(c, dbConn)
+		})
+		projectGroup.PUT("/fs/*path", fileBody, middleware.DecompressBody(), func(c *gin.Context) {
+			handlers.PutFileByPath(c, dbConn)
+		})
+		projectGroup.GET("/structure", func(c *gin.Context) {
 			handlers.GetProjectStructureHandler(c, dbConn)
 		})
+		projectGroup.GET("/tree", func(c *gin.Context) {
+			handlers.GetProjectTree(c, dbConn)
+		})
+		projectGroup.GET("/tree/meta", func(c *gin.Context) {
+			handlers.GetProjectMetaTree(c, dbConn)
+		})
+		projectGroup.GET("/children", func(c *gin.Context) {
+			handlers.ListFolderChildren(c, dbConn)
+		})
+		projectGroup.GET("/files/:fileId/content", func(c *gin.Context) {
+			handlers.GetFileContent(c, dbConn)
+		})
+		projectGroup.GET("/files/:fileId/revisions", func(c *gin.Context) {
+			handlers.ListFileRevisions(c, dbConn)
+		})
+		projectGroup.GET("/files/:fileId/revisions/:version", func(c *gin.Context) {
+			handlers.FetchFileRevision(c, dbConn)
+		})
+		projectGroup.POST("/files/:fileId/revisions/:version/restore", func(c *gin.Context) {
+			handlers.RestoreFileRevision(c, dbConn)
+		})
+		projectGroup.GET("/files/:fileId/diff", func(c *gin.Context) {
+			handlers.DiffFileRevisions(c, dbConn)
+		})
+		projectGroup.GET("/search", func(c *gin.Context) {
+			handlers.SearchSpace(c, dbConn)
+		})
+		projectGroup.POST("/replace/preview", func(c *gin.Context) {
+			handlers.PreviewReplace(c, dbConn)
+		})
+		projectGroup.POST("/replace/apply", idempotent, func(c *gin.Context) {
+			handlers.ApplyReplace(c, dbConn)
+		})
+		projectGroup.GET("/export", func(c *gin.Context) {
+			handlers.ExportSpace(c, dbConn)
+		})
 	}
+	// The data reset is a development and staging tool; production never
+	// mounts it.
+	if !deps.Config.IsProduction() {
+		adminGroup := router.Group("/admin")
+		adminGroup.Use(middleware.AuthMiddleware(), apiLimit, middleware.RequireRole(dbConn, models.RoleAdmin))
+		{
+			adminGroup.POST("/reset", func(c *gin.Context) {
+				handlers.ResetData(c, dbConn, []byte(deps.Config.AdminResetSecret))
+			})
+		}
+	}
+
 	userGroup := router.Group("/user")
-	userGroup.Use(middleware.AuthMiddleware())
+	userGroup.Use(middleware.AuthMiddleware(), apiLimit)
 	{
 		//Tested
 		userGroup.GET("/profile", func(c *gin.Context) {
@@ -88,18 +255,10 @@ func InitializeRoutes(router *gin.Engine, dbConn *database.PostQreSQLCon) {
 				handlers.SubmitSol(c, dbConn)
 
 			})
-			academyGroup.POST("/generate", func(ctx *gin.Context) {
-				handlers.GenerateSkill(ctx, dbConn)
+			academyGroup.POST("/generate", aiLimit, func(ctx *gin.Context) {
+				handlers.GenerateSkill(ctx, dbConn, aiClient)
 			})
 		}
 
 	}
-	//Tested
-	deleteGroup := router.Group("/delete")
-	deleteGroup.Use(middleware.AuthMiddleware())
-	{
-		deleteGroup.GET("/:name", func(c *gin.Context) {
-			handlers.DeleteTables(c, dbConn)
-		})
-	}
 }
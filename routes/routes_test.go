@@ -0,0 +1,76 @@
+package routes
+
+import (
+	"Hack4Change/docs"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+var pathParam = regexp.MustCompile(`[:*](\w+)`)
+
+// openAPIPath converts a gin route such as /space/:id/fs/*path into the
+// OpenAPI form /space/{id}/fs/{path}.
+func openAPIPath(ginPath string) string {
+	return pathParam.ReplaceAllString(ginPath, "{$1}")
+}
+
+func loadSpecPaths(t *testing.T) map[string]map[string]any {
+	t.Helper()
+	var spec struct {
+		Paths map[string]map[string]any `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(docs.OpenAPI, &spec); err != nil {
+		t.Fatalf("parsing openapi.yaml: %v", err)
+	}
+	return spec.Paths
+}
+
+func registeredRoutes() gin.RoutesInfo {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	InitializeRoutes(router, Dependencies{})
+	return router.Routes()
+}
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+	paths := loadSpecPaths(t)
+	for _, route := range registeredRoutes() {
+		path := openAPIPath(route.Path)
+		ops, ok := paths[path]
+		if !ok {
+			// Unversioned API routes are deprecated aliases documented
+			// under /v1.
+			path = "/v1" + path
+			ops, ok = paths[path]
+		}
+		if !ok {
+			t.Errorf("%s %s is registered but %s is missing from docs/openapi.yaml", route.Method, route.Path, path)
+			continue
+		}
+		if _, ok := ops[strings.ToLower(route.Method)]; !ok {
+			t.Errorf("%s %s is registered but the %s operation is missing from docs/openapi.yaml", route.Method, route.Path, strings.ToLower(route.Method))
+		}
+	}
+}
+
+func TestSpecHasNoStaleRoutes(t *testing.T) {
+	registered := map[string]bool{}
+	for _, route := range registeredRoutes() {
+		registered[strings.ToLower(route.Method)+" "+openAPIPath(route.Path)] = true
+	}
+
+	for path, ops := range loadSpecPaths(t) {
+		for method := range ops {
+			if method == "parameters" {
+				continue
+			}
+			if !registered[method+" "+path] {
+				t.Errorf("docs/openapi.yaml documents %s %s but no such route is registered", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Package search matches text and regular expression queries against file
+// content line by line.
+package search
+
+import (
+	"Hack4Change/apperrors"
+	"Hack4Change/models"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Options describe a query. Regex patterns use RE2 syntax; otherwise
+// Pattern is matched literally.
+type Options struct {
+	Pattern    string
+	Regex      bool
+	IgnoreCase bool
+	WholeWord  bool
+}
+
+// Matcher finds the matches of a compiled query.
+type Matcher struct {
+	re        *regexp.Regexp
+	regex     bool
+	wholeWord bool
+	literal   string
+	foldCase  bool
+}
+
+// Compile checks a query and prepares it for matching.
+func Compile(opts Options) (*Matcher, error) {
+	if opts.Pattern == "" {
+		return nil, apperrors.Validation("Invalid query").WithField("q", "is required")
+	}
+	pattern := opts.Pattern
+	if !opts.Regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if opts.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, apperrors.Validation("Invalid regular expression").WithField("q", err.Error())
+	}
+
+	m := &Matcher{re: re, regex: opts.Regex, wholeWord: opts.WholeWord, foldCase: opts.IgnoreCase}
+	if opts.Regex {
+		// (?i) may appear inside the pattern, so only a case-insensitive
+		// prefilter is safe.
+		m.literal, m.foldCase = requiredLiteral(pattern), true
+	} else {
+		m.literal = opts.Pattern
+	}
+	return m, nil
+}
+
+// Literal returns text every matching file contains, for narrowing the
+// files to scan before matching, and whether it must be compared without
+// case. An empty literal means any file may match.
+func (m *Matcher) Literal() (string, bool) {
+	return m.literal, m.foldCase
+}
+
+// requiredLiteral finds the longest literal a regular expression cannot
+// match without, or "" when there is none.
+func requiredLiteral(pattern string) string {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return ""
+	}
+	return literalOf(re.Simplify())
+}
+
+func literalOf(re *syntax.Regexp) string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return string(re.Rune)
+	case syntax.OpCapture, syntax.OpPlus:
+		return literalOf(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min >= 1 {
+			return literalOf(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		longest := ""
+		for _, sub := range re.Sub {
+			if lit := literalOf(sub); len(lit) > len(longest) {
+				longest = lit
+			}
+		}
+		return longest
+	}
+	return ""
+}
+
+// Find returns the byte ranges of the non-empty matches in line, each
+// followed by the ranges of its submatches. With WholeWord a match only
+// counts when no letter, digit or underscore touches either end.
+func (m *Matcher) Find(line string) [][]int {
+	locs := m.re.FindAllStringSubmatchIndex(line, -1)
+	kept := locs[:0]
+	for _, loc := range locs {
+		if loc[0] == loc[1] {
+			continue
+		}
+		if m.wholeWord && !atWordBoundaries(line, loc[0], loc[1]) {
+			continue
+		}
+		kept = append(kept, loc)
+	}
+	return kept
+}
+
+func atWordBoundaries(line string, start, end int) bool {
+	if before, _ := utf8.DecodeLastRuneInString(line[:start]); start > 0 && isWordRune(before) {
+		return false
+	}
+	if after, _ := utf8.DecodeRuneInString(line[end:]); end < len(line) && isWordRune(after) {
+		return false
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Replace replaces every match in content, line by line as Lines finds
+// them, and returns the new content with the number of replacements. For
+// regex queries $1, ${name} and the like in replacement expand to the
+// match's submatches; otherwise replacement is used as it is. Line endings
+// are kept.
+func (m *Matcher) Replace(content, replacement string) (string, int) {
+	var out strings.Builder
+	count := 0
+	for _, chunk := range strings.SplitAfter(content, "\n") {
+		line := strings.TrimSuffix(strings.TrimSuffix(chunk, "\n"), "\r")
+		locs := m.Find(line)
+		if len(locs) == 0 {
+			out.WriteString(chunk)
+			continue
+		}
+		last := 0
+		for _, loc := range locs {
+			out.WriteString(line[last:loc[0]])
+			if m.regex {
+				out.Write(m.re.ExpandString(nil, replacement, line, loc))
+			} else {
+				out.WriteString(replacement)
+			}
+			last = loc[1]
+		}
+		out.WriteString(chunk[last:])
+		count += len(locs)
+	}
+	if count == 0 {
+		return content, 0
+	}
+	return out.String(), count
+}
+
+// Lines returns up to limit matching lines of content, each with context
+// lines on either side, and whether more matching lines were left out.
+func (m *Matcher) Lines(content string, context, limit int) ([]models.SearchLine, bool) {
+	lines := splitLines(content)
+	var out []models.SearchLine
+	for i, line := range lines {
+		locs := m.Find(line)
+		if len(locs) == 0 {
+			continue
+		}
+		if len(out) == limit {
+			return out, true
+		}
+		ranges := make([]models.SearchRange, len(locs))
+		for j, loc := range locs {
+			start := utf8.RuneCountInString(line[:loc[0]])
+			ranges[j] = models.SearchRange{Start: start, End: start + utf8.RuneCountInString(line[loc[0]:loc[1]])}
+		}
+		out = append(out, models.SearchLine{
+			Line:   i + 1,
+			Text:   line,
+			Ranges: ranges,
+			Before: append([]string{}, lines[max(0, i-context):i]...),
+			After:  append([]string{}, lines[i+1:min(len(lines), i+1+context)]...),
+		})
+	}
+	return out, false
+}
+
+// splitLines splits content into lines without their line endings. A
+// final line ending does not start another line.
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
@@ -0,0 +1,197 @@
+package search
+
+import (
+	"Hack4Change/apperrors"
+	"Hack4Change/models"
+	"reflect"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		wantErr  bool
+		literal  string
+		foldCase bool
+	}{
+		{name: "empty pattern", opts: Options{}, wantErr: true},
+		{name: "invalid regex", opts: Options{Pattern: "a(", Regex: true}, wantErr: true},
+		{name: "literal text", opts: Options{Pattern: "a.b("}, literal: "a.b("},
+		{name: "literal ignoring case", opts: Options{Pattern: "Foo", IgnoreCase: true}, literal: "Foo", foldCase: true},
+		{name: "regex longest required literal", opts: Options{Pattern: `func\s+handle\w*`, Regex: true}, literal: "handle", foldCase: true},
+		{name: "regex through a capture and a plus", opts: Options{Pattern: `(abc)+x`, Regex: true}, literal: "abc", foldCase: true},
+		{name: "regex with a counted repeat", opts: Options{Pattern: `(todo){2,3}`, Regex: true}, literal: "todo", foldCase: true},
+		{name: "regex with an optional literal", opts: Options{Pattern: `(abc)?x`, Regex: true}, literal: "x", foldCase: true},
+		{name: "regex with an alternation", opts: Options{Pattern: `foo|bar`, Regex: true}, literal: "", foldCase: true},
+		{name: "regex with only a class", opts: Options{Pattern: `[a-z]+`, Regex: true}, literal: "", foldCase: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Compile(tt.opts)
+			if tt.wantErr {
+				if !apperrors.Is(err, apperrors.KindValidation) {
+					t.Fatalf("Compile() error = %v, want a validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			literal, foldCase := m.Literal()
+			if literal != tt.literal || foldCase != tt.foldCase {
+				t.Errorf("Literal() = %q, %v, want %q, %v", literal, foldCase, tt.literal, tt.foldCase)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		line string
+		want [][]int
+	}{
+		{name: "literal metacharacters", opts: Options{Pattern: "a.b"}, line: "axb a.b", want: [][]int{{4, 7}}},
+		{name: "case sensitive", opts: Options{Pattern: "go"}, line: "Go go", want: [][]int{{3, 5}}},
+		{name: "ignore case", opts: Options{Pattern: "go", IgnoreCase: true}, line: "Go go", want: [][]int{{0, 2}, {3, 5}}},
+		{name: "whole word", opts: Options{Pattern: "id", WholeWord: true}, line: "id idx _id (id)", want: [][]int{{0, 2}, {12, 14}}},
+		{name: "whole word against letters beyond ASCII", opts: Options{Pattern: "na", WholeWord: true}, line: "éna na", want: [][]int{{5, 7}}},
+		{name: "whole word with digits", opts: Options{Pattern: "v", WholeWord: true}, line: "v1 v-", want: [][]int{{3, 4}}},
+		{name: "empty matches are dropped", opts: Options{Pattern: "x*", Regex: true}, line: "axxb", want: [][]int{{1, 3}}},
+		{name: "submatches follow the match", opts: Options{Pattern: `(\w+)=(\d+)`, Regex: true}, line: "a=1", want: [][]int{{0, 3, 0, 1, 2, 3}}},
+		{name: "no match", opts: Options{Pattern: "zzz"}, line: "abc", want: [][]int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Compile(tt.opts)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			got := m.Find(tt.line)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find(%q) = %v, want %v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLines(t *testing.T) {
+	content := "one\r\ntwo café\r\nthree café café\nfour\nfive\n"
+	tests := []struct {
+		name          string
+		opts          Options
+		content       string
+		context       int
+		limit         int
+		want          []models.SearchLine
+		wantTruncated bool
+	}{
+		{
+			name: "ranges count runes and line endings are dropped",
+			opts: Options{Pattern: "café"}, content: content, context: 1, limit: 10,
+			want: []models.SearchLine{
+				{Line: 2, Text: "two café", Ranges: []models.SearchRange{{Start: 4, End: 8}}, Before: []string{"one"}, After: []string{"three café café"}},
+				{Line: 3, Text: "three café café", Ranges: []models.SearchRange{{Start: 6, End: 10}, {Start: 11, End: 15}}, Before: []string{"two café"}, After: []string{"four"}},
+			},
+		},
+		{
+			name: "context stops at the ends of the file",
+			opts: Options{Pattern: "five"}, content: content, context: 3, limit: 10,
+			want: []models.SearchLine{
+				{Line: 5, Text: "five", Ranges: []models.SearchRange{{Start: 0, End: 4}}, Before: []string{"two café", "three café café", "four"}, After: []string{}},
+			},
+		},
+		{
+			name: "limit truncates",
+			opts: Options{Pattern: "café"}, content: content, context: 0, limit: 1,
+			want: []models.SearchLine{
+				{Line: 2, Text: "two café", Ranges: []models.SearchRange{{Start: 4, End: 8}}, Before: []string{}, After: []string{}},
+			},
+			wantTruncated: true,
+		},
+		{
+			name: "no final newline",
+			opts: Options{Pattern: "end"}, content: "start\nend", context: 1, limit: 10,
+			want: []models.SearchLine{
+				{Line: 2, Text: "end", Ranges: []models.SearchRange{{Start: 0, End: 3}}, Before: []string{"start"}, After: []string{}},
+			},
+		},
+		{name: "empty content", opts: Options{Pattern: "x"}, content: "", context: 2, limit: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Compile(tt.opts)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			got, truncated := m.Lines(tt.content, tt.context, tt.limit)
+			if !reflect.DeepEqual(got, tt.want) || truncated != tt.wantTruncated {
+				t.Errorf("Lines() = %+v, %v, want %+v, %v", got, truncated, tt.want, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestReplace(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		content     string
+		replacement string
+		want        string
+		wantCount   int
+	}{
+		{
+			name: "CRLF endings are kept", opts: Options{Pattern: "old"},
+			content: "old\r\nkeep\r\nold old\r\n", replacement: "new",
+			want: "new\r\nkeep\r\nnew new\r\n", wantCount: 3,
+		},
+		{
+			name: "no final newline", opts: Options{Pattern: "b"},
+			content: "a\nb", replacement: "c",
+			want: "a\nc", wantCount: 1,
+		},
+		{
+			name: "regex expands submatches", opts: Options{Pattern: `(\w+)=(\d+)`, Regex: true},
+			content: "a=1, b=2\n", replacement: "$2=$1",
+			want: "1=a, 2=b\n", wantCount: 2,
+		},
+		{
+			name: "regex expands named submatches", opts: Options{Pattern: `get(?P<name>\w+)\(\)`, Regex: true},
+			content: "x := getName()\n", replacement: "${name}",
+			want: "x := Name\n", wantCount: 1,
+		},
+		{
+			name: "literal replacement is not expanded", opts: Options{Pattern: "price"},
+			content: "price\n", replacement: "$1 ${name}",
+			want: "$1 ${name}\n", wantCount: 1,
+		},
+		{
+			name: "whole word leaves longer words", opts: Options{Pattern: "id", WholeWord: true},
+			content: "id idx\n", replacement: "key",
+			want: "key idx\n", wantCount: 1,
+		},
+		{
+			name: "a match does not span lines", opts: Options{Pattern: `a\s+b`, Regex: true},
+			content: "a\nb\n", replacement: "x",
+			want: "a\nb\n", wantCount: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Compile(tt.opts)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			got, count := m.Replace(tt.content, tt.replacement)
+			if got != tt.want || count != tt.wantCount {
+				t.Errorf("Replace() = %q, %d, want %q, %d", got, count, tt.want, tt.wantCount)
+			}
+		})
+	}
+}
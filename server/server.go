@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"Hack4Change/ai"
+	"Hack4Change/background"
+	"Hack4Change/config"
+	db "Hack4Change/database"
+	"Hack4Change/ratelimit"
+	routes "Hack4Change/routes"
+	"Hack4Change/tracing"
+	"Hack4Change/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Run starts the HTTP API and blocks until ctx is cancelled (normally by
+// SIGINT/SIGTERM). Shutdown then happens in order: /readyz starts failing,
+// the listener stops accepting connections and in-flight requests drain,
+// background jobs finish, and finally the database pool and tracer are
+// closed.
+func Run(ctx context.Context, cfg config.Config) error {
+	if cfg.IsProduction() {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("setting up tracing: %w", err)
+	}
+
+	dbConn, err := db.ConnectPostgreSQL(cfg.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("connecting to postgresql: %w", err)
+	}
+
+	if err := validation.Setup(); err != nil {
+		return fmt.Errorf("setting up request validation: %w", err)
+	}
+
+	if cfg.AdminResetSecret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return fmt.Errorf("generating admin reset secret: %w", err)
+		}
+		cfg.AdminResetSecret = string(secret)
+	}
+
+	jobs := background.NewGroup()
+	var draining atomic.Bool
+
+	rateLimiter, rateLimits, err := setupRateLimiting(cfg, dbConn)
+	if err != nil {
+		return err
+	}
+	jobs.Every("ratelimit-cleanup", time.Minute, func(ctx context.Context) error {
+		return rateLimiter.Cleanup(ctx, rateLimitIdle)
+	})
+	jobs.Every("idempotency-cleanup", 10*time.Minute, func(ctx context.Context) error {
+		return dbConn.DeleteExpiredIdempotencyKeys(ctx, cfg.IdempotencyKeyTTL)
+	})
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+	routes.InitializeRoutes(router, routes.Dependencies{
+		DB:       dbConn,
+		AI:       ai.NewClient(cfg.AIServiceURL, cfg.AITimeout),
+		Config:   cfg,
+		Draining: &draining,
+
+		RateLimiter: rateLimiter,
+		RateLimits:  rateLimits,
+	})
+
+	srv := &http.Server{
+		Addr:              cfg.HTTPAddr,
+		Handler:           router,
+		ReadHeaderTimeout: cfg.HTTPReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTPReadTimeout,
+		WriteTimeout:      cfg.HTTPWriteTimeout,
+		IdleTimeout:       cfg.HTTPIdleTimeout,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("HTTP server listening", "addr", cfg.HTTPAddr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+		close(serveErr)
+	}()
+
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			return fmt.Errorf("http server: %w", err)
+		}
+	case <-ctx.Done():
+		slog.Info("Shutdown signal received, draining")
+	}
+
+	draining.Store(true)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("draining http server: %w", err))
+	}
+	if err := jobs.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("waiting for background jobs: %w", err))
+	}
+	if err := dbConn.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("closing database pool: %w", err))
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("flushing traces: %w", err))
+	}
+
+	slog.Info("Shutdown complete")
+	return errors.Join(errs...)
+}
+
+// rateLimitIdle is how long a bucket may go unused before cleanup forgets it.
+const rateLimitIdle = time.Hour
+
+func setupRateLimiting(cfg config.Config, dbConn *db.PostQreSQLCon) (ratelimit.Store, routes.RateLimits, error) {
+	var limits routes.RateLimits
+	for _, l := range []struct {
+		name  string
+		value string
+		dest  *ratelimit.Limit
+	}{
+		{"RATE_LIMIT_AUTH", cfg.RateLimitAuth, &limits.Auth},
+		{"RATE_LIMIT_API", cfg.RateLimitAPI, &limits.API},
+		{"RATE_LIMIT_AI_GENERATE", cfg.RateLimitAIGenerate, &limits.AIGenerate},
+	} {
+		limit, err := ratelimit.ParseLimit(l.value)
+		if err != nil {
+			return nil, limits, fmt.Errorf("%s: %w", l.name, err)
+		}
+		*l.dest = limit
+	}
+
+	switch cfg.RateLimitStore {
+	case "memory":
+		return ratelimit.NewMemoryStore(), limits, nil
+	case "postgres":
+		return ratelimit.NewPostgresStore(dbConn), limits, nil
+	}
+	return nil, limits, fmt.Errorf("RATE_LIMIT_STORE: unknown store %q", cfg.RateLimitStore)
+}
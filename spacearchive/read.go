@@ -0,0 +1,114 @@
+package spacearchive
+
+import (
+	"Hack4Change/apperrors"
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+)
+
+// DetectFormat recognises a zip or tar.gz archive by its leading bytes.
+func DetectFormat(data []byte) (string, bool) {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")), bytes.HasPrefix(data, []byte("PK\x05\x06")):
+		return FormatZip, true
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		return FormatTarGz, true
+	}
+	return "", false
+}
+
+// Read extracts an archive held in memory. Binary files, symlinks and other
+// special entries are skipped and listed in Tree.Skipped; unsafe paths and
+// broken limits fail the whole archive.
+func Read(data []byte, format string, limits Limits) (Tree, error) {
+	switch format {
+	case FormatZip:
+		return readZip(data, limits)
+	case FormatTarGz:
+		return readTarGz(bytes.NewReader(data), limits)
+	}
+	return Tree{}, apperrors.Validation("Unsupported archive format").
+		WithField("format", "must be zip or tar.gz")
+}
+
+func readZip(data []byte, limits Limits) (Tree, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return Tree{}, readError(err)
+	}
+	b := NewBuilder(limits)
+	for _, f := range zr.File {
+		if err := b.Entry(); err != nil {
+			return Tree{}, err
+		}
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			err = b.AddDir(f.Name)
+		case mode&fs.ModeSymlink != 0:
+			err = b.SkipEntry(f.Name, SkipSymlink)
+		case !mode.IsRegular():
+			err = b.SkipEntry(f.Name, SkipSpecial)
+		default:
+			err = addZipFile(b, f)
+		}
+		if err != nil {
+			return Tree{}, err
+		}
+	}
+	return b.Finish(), nil
+}
+
+func addZipFile(b *Builder, f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return readError(err)
+	}
+	defer rc.Close()
+	return b.AddFile(f.Name, rc, f.Modified)
+}
+
+func readTarGz(r io.Reader, limits Limits) (Tree, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return Tree{}, readError(err)
+	}
+	defer gz.Close()
+
+	b := NewBuilder(limits)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Tree{}, readError(err)
+		}
+		if hdr.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		if err := b.Entry(); err != nil {
+			return Tree{}, err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = b.AddDir(hdr.Name)
+		case tar.TypeReg, tar.TypeRegA:
+			err = b.AddFile(hdr.Name, tr, hdr.ModTime)
+		case tar.TypeSymlink, tar.TypeLink:
+			err = b.SkipEntry(hdr.Name, SkipSymlink)
+		default:
+			err = b.SkipEntry(hdr.Name, SkipSpecial)
+		}
+		if err != nil {
+			return Tree{}, err
+		}
+	}
+	return b.Finish(), nil
+}
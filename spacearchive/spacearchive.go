@@ -0,0 +1,261 @@
+// Package spacearchive converts between a space's folder tree and zip or
+// tar.gz archives.
+package spacearchive
+
+import (
+	"Hack4Change/apperrors"
+	"Hack4Change/filetype"
+	"Hack4Change/models"
+	"Hack4Change/validation"
+	"bytes"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Archive formats.
+const (
+	FormatZip   = "zip"
+	FormatTarGz = "tar.gz"
+)
+
+// Limits bound what Read accepts. Sizes count decompressed bytes, so a
+// small archive cannot expand past them.
+type Limits struct {
+	MaxEntries    int
+	MaxTotalBytes int64
+	MaxFileBytes  int64
+}
+
+// File is one file in a Tree, addressed by its slash-separated path.
+type File struct {
+	Path    string
+	Content string
+	ModTime time.Time
+}
+
+// Tree is the contents of an archive. Dirs holds every folder, including
+// ones only implied by file paths, with parents before children.
+type Tree struct {
+	Dirs    []string
+	Files   []File
+	Skipped []models.SkippedEntry
+}
+
+// Skip reasons.
+const (
+	SkipBinary  = "binary"
+	SkipSymlink = "symlink"
+	SkipSpecial = "not a regular file"
+)
+
+// cleanPath turns an archive entry name into a path inside the space, or
+// rejects it. Absolute paths, ".." segments and backslashes are how zip-slip
+// archives escape the extraction root, so none of them are tolerated, even
+// where they would resolve harmlessly.
+func cleanPath(name string) (string, error) {
+	invalid := func(reason string) error {
+		return apperrors.Validation("Archive contains an unsafe path").
+			WithField("archive", strconv.Quote(name)+" "+reason)
+	}
+	if strings.Contains(name, `\`) {
+		return "", invalid("contains a backslash")
+	}
+	if strings.HasPrefix(name, "/") || (len(name) > 1 && name[1] == ':') {
+		return "", invalid("is absolute")
+	}
+	name = strings.TrimSuffix(name, "/")
+	var segments []string
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "." {
+			continue
+		}
+		segments = append(segments, segment)
+		if segment == ".." {
+			return "", invalid("leaves the archive root")
+		}
+		if !validation.IsSafeFilename(segment) {
+			return "", invalid("is not a valid file name")
+		}
+	}
+	return path.Join(segments...), nil
+}
+
+// Builder collects the entries of an archive, or of any other source of
+// files such as a git tree, into a Tree while enforcing the limits.
+type Builder struct {
+	limits Limits
+	dirs   map[string]bool
+	files  map[string]File
+	total  int64
+	count  int
+	tree   Tree
+}
+
+// NewBuilder returns a Builder bounded by limits.
+func NewBuilder(limits Limits) *Builder {
+	return &Builder{limits: limits, dirs: map[string]bool{}, files: map[string]File{}}
+}
+
+// Entry counts one entry against MaxEntries.
+func (b *Builder) Entry() error {
+	b.count++
+	if b.limits.MaxEntries > 0 && b.count > b.limits.MaxEntries {
+		return apperrors.PayloadTooLarge("Archive has too many entries").
+			WithField("archive", "may hold at most "+strconv.Itoa(b.limits.MaxEntries)+" entries")
+	}
+	return nil
+}
+
+// fileLimit is how many bytes the next file may have before one limit or
+// the other is exceeded, or -1 when neither is set.
+func (b *Builder) fileLimit() int64 {
+	limit := int64(-1)
+	if b.limits.MaxFileBytes > 0 {
+		limit = b.limits.MaxFileBytes
+	}
+	if b.limits.MaxTotalBytes > 0 {
+		if remaining := b.limits.MaxTotalBytes - b.total; limit < 0 || remaining < limit {
+			limit = remaining
+		}
+	}
+	return limit
+}
+
+// tooLarge explains which limit a file of more than fileLimit bytes broke.
+func (b *Builder) tooLarge(name string) error {
+	if b.limits.MaxFileBytes > 0 && b.fileLimit() == b.limits.MaxFileBytes {
+		return apperrors.PayloadTooLarge("Archive contains a file that is too large").
+			WithField("archive", strconv.Quote(name)+" is larger than "+strconv.FormatInt(b.limits.MaxFileBytes, 10)+" bytes")
+	}
+	return apperrors.PayloadTooLarge("Archive is too large once extracted").
+		WithField("archive", "may hold at most "+strconv.FormatInt(b.limits.MaxTotalBytes, 10)+" bytes of files")
+}
+
+// AddDir records a folder and every folder above it.
+func (b *Builder) AddDir(name string) error {
+	p, err := cleanPath(name)
+	if err != nil || p == "" {
+		return err
+	}
+	return b.ensureDirs(p)
+}
+
+// ensureDirs records p and every folder above it.
+func (b *Builder) ensureDirs(p string) error {
+	for dir := p; dir != "." && !b.dirs[dir]; dir = path.Dir(dir) {
+		if _, ok := b.files[dir]; ok {
+			return conflict(dir)
+		}
+		b.dirs[dir] = true
+	}
+	return nil
+}
+
+// AddFile reads one file entry. Content with a NUL byte near the start is
+// skipped as binary before the rest is read, so large images and the like
+// cost nothing against the size limits.
+func (b *Builder) AddFile(name string, r io.Reader, modTime time.Time) error {
+	p, err := cleanPath(name)
+	if err != nil {
+		return err
+	}
+	if p == "" {
+		return apperrors.Validation("Archive contains a file without a name")
+	}
+
+	head := make([]byte, filetype.SniffLen)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return readError(err)
+	}
+	head = head[:n]
+	if bytes.IndexByte(head, 0) >= 0 {
+		b.skip(p, SkipBinary)
+		return nil
+	}
+	limit := b.fileLimit()
+	if limit >= 0 && int64(len(head)) > limit {
+		return b.tooLarge(p)
+	}
+	rest := io.Reader(r)
+	if limit >= 0 {
+		// One byte past the limit tells an oversized file from one that
+		// fits exactly.
+		rest = io.LimitReader(r, limit-int64(len(head))+1)
+	}
+	tail, err := io.ReadAll(rest)
+	if err != nil {
+		return readError(err)
+	}
+	content := append(head, tail...)
+	if limit >= 0 && int64(len(content)) > limit {
+		return b.tooLarge(p)
+	}
+	if filetype.IsBinary(content) {
+		b.skip(p, SkipBinary)
+		return nil
+	}
+
+	if b.dirs[p] {
+		return conflict(p)
+	}
+	if _, ok := b.files[p]; ok {
+		return apperrors.Validation("Archive contains the same path twice").
+			WithField("archive", strconv.Quote(p)+" appears more than once")
+	}
+	if dir := path.Dir(p); dir != "." {
+		if err := b.ensureDirs(dir); err != nil {
+			return err
+		}
+	}
+	b.total += int64(len(content))
+	b.files[p] = File{Path: p, Content: string(content), ModTime: modTime}
+	return nil
+}
+
+// SkipEntry records an entry that is not a regular file or folder.
+func (b *Builder) SkipEntry(name, reason string) error {
+	p, err := cleanPath(name)
+	if err != nil {
+		return err
+	}
+	b.skip(p, reason)
+	return nil
+}
+
+func readError(err error) error {
+	return apperrors.Wrap(apperrors.KindValidation, err, "Archive is corrupt or unreadable")
+}
+
+func (b *Builder) skip(name, reason string) {
+	b.tree.Skipped = append(b.tree.Skipped, models.SkippedEntry{Path: name, Reason: reason})
+}
+
+func conflict(p string) error {
+	return apperrors.Validation("Archive uses a path as both a file and a folder").
+		WithField("archive", strconv.Quote(p)+" is both a file and a folder")
+}
+
+// Finish sorts the collected entries: folders by depth then path, so
+// parents come first, and files by path.
+func (b *Builder) Finish() Tree {
+	for dir := range b.dirs {
+		b.tree.Dirs = append(b.tree.Dirs, dir)
+	}
+	sort.Slice(b.tree.Dirs, func(i, j int) bool {
+		di, dj := strings.Count(b.tree.Dirs[i], "/"), strings.Count(b.tree.Dirs[j], "/")
+		if di != dj {
+			return di < dj
+		}
+		return b.tree.Dirs[i] < b.tree.Dirs[j]
+	})
+	for _, file := range b.files {
+		b.tree.Files = append(b.tree.Files, file)
+	}
+	sort.Slice(b.tree.Files, func(i, j int) bool { return b.tree.Files[i].Path < b.tree.Files[j].Path })
+	return b.tree
+}
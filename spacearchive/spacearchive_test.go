@@ -0,0 +1,276 @@
+package spacearchive
+
+import (
+	"Hack4Change/apperrors"
+	"Hack4Change/models"
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCleanPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "src/main.go", want: "src/main.go"},
+		{name: "src/", want: "src"},
+		{name: "./src/./main.go", want: "src/main.go"},
+		{name: ".", want: ""},
+		{name: "../evil", wantErr: true},
+		{name: "src/../../evil", wantErr: true},
+		{name: "src/..", wantErr: true},
+		{name: "/etc/passwd", wantErr: true},
+		{name: `C:\evil`, wantErr: true},
+		{name: "C:/evil", wantErr: true},
+		{name: "c:evil", wantErr: true},
+		{name: `src\main.go`, wantErr: true},
+		{name: `..\evil`, wantErr: true},
+		{name: "src//main.go", wantErr: true},
+		{name: "src/ main.go", wantErr: true},
+		{name: "src/ma\x00in.go", wantErr: true},
+		{name: "src/" + strings.Repeat("a", 256), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cleanPath(tt.name)
+			if tt.wantErr {
+				if !apperrors.Is(err, apperrors.KindValidation) {
+					t.Errorf("cleanPath(%q) = %q, %v, want a validation error", tt.name, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("cleanPath(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuilderLimits(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		limits  Limits
+		entries int
+		files   []string
+		wantErr string
+	}{
+		{name: "within every limit", limits: Limits{MaxEntries: 3, MaxTotalBytes: 10, MaxFileBytes: 5}, entries: 3, files: []string{"12345", "12345"}},
+		{name: "too many entries", limits: Limits{MaxEntries: 2}, entries: 3, wantErr: "Archive has too many entries"},
+		{name: "file over its limit", limits: Limits{MaxFileBytes: 4}, files: []string{"12345"}, wantErr: "Archive contains a file that is too large"},
+		{name: "total over its limit", limits: Limits{MaxTotalBytes: 8, MaxFileBytes: 5}, files: []string{"12345", "12345"}, wantErr: "Archive is too large once extracted"},
+		{name: "total ends exactly at the limit", limits: Limits{MaxTotalBytes: 10}, files: []string{"12345", "12345"}},
+		{
+			name:   "file over the limit past the sniffed head",
+			limits: Limits{MaxFileBytes: 1000}, files: []string{strings.Repeat("a", 1001)},
+			wantErr: "Archive contains a file that is too large",
+		},
+		{name: "no limits", files: []string{strings.Repeat("a", 1<<16)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBuilder(tt.limits)
+			var err error
+			for i := 0; i < tt.entries && err == nil; i++ {
+				err = b.Entry()
+			}
+			for i, content := range tt.files {
+				if err != nil {
+					break
+				}
+				err = b.AddFile("f"+string(rune('a'+i)), strings.NewReader(content), now)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("err = %v, want none", err)
+				}
+				if got := len(b.Finish().Files); got != len(tt.files) {
+					t.Errorf("got %d files, want %d", got, len(tt.files))
+				}
+				return
+			}
+			if !apperrors.Is(err, apperrors.KindPayloadTooLarge) || apperrors.From(err).Message != tt.wantErr {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuilderSkipsBinaryFiles(t *testing.T) {
+	b := NewBuilder(Limits{MaxTotalBytes: 4})
+	// The binary file is skipped before the size limits are applied.
+	if err := b.AddFile("image.png", strings.NewReader("\x89PNG\x00"+strings.Repeat("x", 100)), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AddFile("a.txt", strings.NewReader("text"), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	tree := b.Finish()
+	if len(tree.Files) != 1 || tree.Files[0].Path != "a.txt" {
+		t.Errorf("files = %+v, want only a.txt", tree.Files)
+	}
+	want := []models.SkippedEntry{{Path: "image.png", Reason: SkipBinary}}
+	if !reflect.DeepEqual(tree.Skipped, want) {
+		t.Errorf("skipped = %+v, want %+v", tree.Skipped, want)
+	}
+}
+
+func TestBuilderConflicts(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(b *Builder) error
+	}{
+		{name: "file then folder", build: func(b *Builder) error {
+			if err := b.AddFile("a", strings.NewReader("x"), time.Time{}); err != nil {
+				return nil
+			}
+			return b.AddDir("a/b")
+		}},
+		{name: "folder then file", build: func(b *Builder) error {
+			if err := b.AddDir("a"); err != nil {
+				return nil
+			}
+			return b.AddFile("a", strings.NewReader("x"), time.Time{})
+		}},
+		{name: "same file twice", build: func(b *Builder) error {
+			if err := b.AddFile("a", strings.NewReader("x"), time.Time{}); err != nil {
+				return nil
+			}
+			return b.AddFile("./a", strings.NewReader("y"), time.Time{})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.build(NewBuilder(Limits{})); !apperrors.Is(err, apperrors.KindValidation) {
+				t.Errorf("err = %v, want a validation error", err)
+			}
+		})
+	}
+}
+
+func TestReadSkipsSpecialEntries(t *testing.T) {
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	addZip := func(name string, mode fs.FileMode, content string) {
+		hdr := &zip.FileHeader{Name: name}
+		hdr.SetMode(mode)
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	addZip("main.go", 0o644, "package main\n")
+	addZip("link", fs.ModeSymlink|0o777, "main.go")
+	addZip("pipe", fs.ModeNamedPipe|0o644, "")
+	addZip("blob.bin", 0o644, "\x00\x01\x02")
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var tarBuf bytes.Buffer
+	gz := gzip.NewWriter(&tarBuf)
+	tw := tar.NewWriter(gz)
+	addTar := func(hdr *tar.Header, content string) {
+		hdr.Size = int64(len(content))
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	addTar(&tar.Header{Typeflag: tar.TypeReg, Name: "main.go", Mode: 0o644}, "package main\n")
+	addTar(&tar.Header{Typeflag: tar.TypeSymlink, Name: "link", Linkname: "main.go"}, "")
+	addTar(&tar.Header{Typeflag: tar.TypeFifo, Name: "pipe"}, "")
+	addTar(&tar.Header{Typeflag: tar.TypeReg, Name: "blob.bin", Mode: 0o644}, "\x00\x01\x02")
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	wantSkipped := []models.SkippedEntry{
+		{Path: "link", Reason: SkipSymlink},
+		{Path: "pipe", Reason: SkipSpecial},
+		{Path: "blob.bin", Reason: SkipBinary},
+	}
+	for format, data := range map[string][]byte{FormatZip: zipBuf.Bytes(), FormatTarGz: tarBuf.Bytes()} {
+		t.Run(format, func(t *testing.T) {
+			if got, ok := DetectFormat(data); !ok || got != format {
+				t.Errorf("DetectFormat() = %q, %v, want %q", got, ok, format)
+			}
+			tree, err := Read(data, format, Limits{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tree.Files) != 1 || tree.Files[0].Path != "main.go" {
+				t.Errorf("files = %+v, want only main.go", tree.Files)
+			}
+			if !reflect.DeepEqual(tree.Skipped, wantSkipped) {
+				t.Errorf("skipped = %+v, want %+v", tree.Skipped, wantSkipped)
+			}
+		})
+	}
+}
+
+func TestReadRejectsUnsafePaths(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if _, err := zw.Create("../evil.sh"); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(buf.Bytes(), FormatZip, Limits{}); !apperrors.Is(err, apperrors.KindValidation) {
+		t.Errorf("Read() err = %v, want a validation error", err)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	modTime := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	tree := Tree{
+		Dirs: []string{"empty", "src", "src/pkg"},
+		Files: []File{
+			{Path: "README.md", Content: "# Space\n", ModTime: modTime},
+			{Path: "src/main.go", Content: "package main\n\nfunc main() {}\n", ModTime: modTime},
+			{Path: "src/pkg/util.go", Content: "package pkg\r\n", ModTime: modTime.Add(time.Hour)},
+			{Path: "src/empty.txt", Content: "", ModTime: modTime},
+		},
+	}
+	want := tree
+	want.Files = []File{tree.Files[0], tree.Files[3], tree.Files[1], tree.Files[2]}
+
+	for _, format := range []string{FormatZip, FormatTarGz} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, format, tree, modTime); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			got, err := Read(buf.Bytes(), format, Limits{MaxEntries: 10, MaxTotalBytes: 1 << 20, MaxFileBytes: 1 << 10})
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.Dirs, want.Dirs) || len(got.Skipped) != 0 {
+				t.Errorf("dirs = %v, skipped = %v, want %v and none", got.Dirs, got.Skipped, want.Dirs)
+			}
+			if len(got.Files) != len(want.Files) {
+				t.Fatalf("files = %+v, want %+v", got.Files, want.Files)
+			}
+			for i, file := range got.Files {
+				w := want.Files[i]
+				if file.Path != w.Path || file.Content != w.Content || !file.ModTime.Equal(w.ModTime) {
+					t.Errorf("file %d = %+v, want %+v", i, file, w)
+				}
+			}
+		})
+	}
+}
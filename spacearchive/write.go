@@ -0,0 +1,107 @@
+package spacearchive
+
+import (
+	"Hack4Change/apperrors"
+	"Hack4Change/models"
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"path"
+	"time"
+)
+
+// ContentType is the media type of an archive format.
+func ContentType(format string) string {
+	if format == FormatTarGz {
+		return "application/gzip"
+	}
+	return "application/zip"
+}
+
+// FromProject flattens a project tree into archive paths.
+func FromProject(tree models.ProjectTree) Tree {
+	var out Tree
+	addFiles := func(dir string, files []models.File) {
+		for _, file := range files {
+			out.Files = append(out.Files, File{
+				Path:    path.Join(dir, file.FileName),
+				Content: file.FileContent,
+				ModTime: file.UpdatedAt,
+			})
+		}
+	}
+	var walk func(dir string, folders []models.TreeFolder)
+	walk = func(dir string, folders []models.TreeFolder) {
+		for _, folder := range folders {
+			p := path.Join(dir, folder.FolderName)
+			out.Dirs = append(out.Dirs, p)
+			addFiles(p, folder.Files)
+			walk(p, folder.Folders)
+		}
+	}
+	addFiles("", tree.Files)
+	walk("", tree.Folders)
+	return out
+}
+
+// Write streams tree to w as an archive. Folders get entries of their own
+// so empty ones survive the round trip.
+func Write(w io.Writer, format string, tree Tree, modTime time.Time) error {
+	switch format {
+	case FormatZip:
+		return writeZip(w, tree, modTime)
+	case FormatTarGz:
+		return writeTarGz(w, tree, modTime)
+	}
+	return apperrors.Validation("Unsupported archive format").
+		WithField("format", "must be zip or tar.gz")
+}
+
+func writeZip(w io.Writer, tree Tree, modTime time.Time) error {
+	zw := zip.NewWriter(w)
+	for _, dir := range tree.Dirs {
+		hdr := &zip.FileHeader{Name: dir + "/", Method: zip.Store, Modified: modTime}
+		hdr.SetMode(fs.ModeDir | 0o755)
+		if _, err := zw.CreateHeader(hdr); err != nil {
+			return err
+		}
+	}
+	for _, file := range tree.Files {
+		hdr := &zip.FileHeader{Name: file.Path, Method: zip.Deflate, Modified: file.ModTime}
+		hdr.SetMode(0o644)
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, file.Content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func writeTarGz(w io.Writer, tree Tree, modTime time.Time) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, dir := range tree.Dirs {
+		hdr := &tar.Header{Typeflag: tar.TypeDir, Name: dir + "/", Mode: 0o755, ModTime: modTime}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+	}
+	for _, file := range tree.Files {
+		hdr := &tar.Header{Typeflag: tar.TypeReg, Name: file.Path, Mode: 0o644, Size: int64(len(file.Content)), ModTime: file.ModTime}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(tw, file.Content); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
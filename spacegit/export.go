@@ -0,0 +1,320 @@
+package spacegit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/revlist"
+	"github.com/go-git/go-git/v5/storage"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// BundleExt marks a path as a git bundle rather than a repository.
+const BundleExt = ".bundle"
+
+// ExportResult reports what Export wrote.
+type ExportResult struct {
+	Branch string
+	// Commit is the branch's tip after the export.
+	Commit string
+	// Commits counts the commits written. Snapshots whose files match the
+	// commit before them are not committed again.
+	Commits int
+}
+
+// Export commits snapshots, oldest first, on top of branch in the
+// repository at dest. A dest ending in .bundle is written as a new bundle
+// file instead. A missing dest is created as a bare repository; an existing
+// one only gains objects and the branch ref. A non-bare repository's index
+// and work tree are never touched, so exporting to the branch it has
+// checked out is refused: the checkout would show the export reversed as
+// uncommitted changes.
+func Export(dest, branch string, snapshots []Snapshot) (ExportResult, error) {
+	if branch == "" {
+		branch = DefaultBranch
+	}
+	result := ExportResult{Branch: branch}
+	refName := plumbing.NewBranchReferenceName(branch)
+	if refName.Validate() != nil {
+		return result, fmt.Errorf("invalid branch name %q", branch)
+	}
+
+	var repo *git.Repository
+	var err error
+	bundle := strings.HasSuffix(dest, BundleExt)
+	if bundle {
+		repo, err = git.Init(memory.NewStorage(), nil)
+	} else {
+		repo, err = openOrInit(dest)
+	}
+	if err != nil {
+		return result, err
+	}
+	bare := bundle
+	if !bundle {
+		if bare, err = checkNotCheckedOut(repo, dest, refName); err != nil {
+			return result, err
+		}
+	}
+
+	tip := plumbing.ZeroHash
+	var tipTree plumbing.Hash
+	if ref, err := repo.Reference(refName, true); err == nil {
+		commit, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			return result, fmt.Errorf("reading %s: %w", branch, err)
+		}
+		tip, tipTree = commit.Hash, commit.TreeHash
+	} else if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return result, fmt.Errorf("reading %s: %w", branch, err)
+	}
+
+	w := &objectWriter{s: repo.Storer, blobs: map[string]plumbing.Hash{}}
+	for _, snapshot := range snapshots {
+		treeHash, err := w.writeSnapshot(snapshot)
+		if err != nil {
+			return result, err
+		}
+		if tip != plumbing.ZeroHash && treeHash == tipTree {
+			continue
+		}
+		if tip, err = w.writeCommit(snapshot, treeHash, tip); err != nil {
+			return result, err
+		}
+		tipTree = treeHash
+		result.Commits++
+	}
+	if tip == plumbing.ZeroHash {
+		return result, errors.New("nothing to export: the space has no files")
+	}
+	result.Commit = tip.String()
+
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(refName, tip)); err != nil {
+		return result, fmt.Errorf("updating %s: %w", branch, err)
+	}
+	if bare {
+		if err := pointHead(repo, refName); err != nil {
+			return result, err
+		}
+	}
+	if bundle {
+		return result, writeBundleFile(dest, repo.Storer, refName, tip)
+	}
+	return result, nil
+}
+
+// openOrInit opens the repository at dir, creating a bare one when dir does
+// not exist yet. An existing directory that is not a repository is an
+// error rather than something to write into.
+func openOrInit(dir string) (*git.Repository, error) {
+	repo, err := git.PlainOpen(dir)
+	if err == nil {
+		return repo, nil
+	}
+	if !errors.Is(err, git.ErrRepositoryNotExists) {
+		return nil, fmt.Errorf("opening repository %s: %w", dir, err)
+	}
+	if _, statErr := os.Stat(dir); statErr == nil {
+		return nil, fmt.Errorf("%s exists but is not a git repository", dir)
+	}
+	repo, err = git.PlainInit(dir, true)
+	if err != nil {
+		return nil, fmt.Errorf("creating repository %s: %w", dir, err)
+	}
+	return repo, nil
+}
+
+// checkNotCheckedOut reports whether repo is bare and, when it is not,
+// fails if HEAD names branch, even before the branch has a commit.
+func checkNotCheckedOut(repo *git.Repository, dir string, branch plumbing.ReferenceName) (bool, error) {
+	cfg, err := repo.Config()
+	if err != nil {
+		return false, fmt.Errorf("reading config of %s: %w", dir, err)
+	}
+	if cfg.Core.IsBare {
+		return true, nil
+	}
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return false, fmt.Errorf("reading HEAD of %s: %w", dir, err)
+	}
+	if head.Type() == plumbing.SymbolicReference && head.Target() == branch {
+		return false, fmt.Errorf("%s is checked out in %s; export to another branch or to a bare repository", branch.Short(), dir)
+	}
+	return false, nil
+}
+
+// pointHead makes HEAD of a bare repository name branch when it points
+// nowhere yet, as in a freshly created one, so clones check the export out.
+func pointHead(repo *git.Repository, branch plumbing.ReferenceName) error {
+	if _, err := repo.Reference(plumbing.HEAD, true); err == nil {
+		return nil
+	}
+	if err := repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branch)); err != nil {
+		return fmt.Errorf("updating HEAD: %w", err)
+	}
+	return nil
+}
+
+// objectWriter stores blobs, trees and commits. Blobs are remembered by
+// content so a history export stores each version of a file once.
+type objectWriter struct {
+	s     storage.Storer
+	blobs map[string]plumbing.Hash
+}
+
+// dirNode is one directory of a snapshot while its tree is being built.
+type dirNode struct {
+	files map[string]plumbing.Hash
+	dirs  map[string]*dirNode
+}
+
+func newDirNode() *dirNode {
+	return &dirNode{files: map[string]plumbing.Hash{}, dirs: map[string]*dirNode{}}
+}
+
+func (w *objectWriter) writeSnapshot(snapshot Snapshot) (plumbing.Hash, error) {
+	root := newDirNode()
+	for _, file := range snapshot.Files {
+		hash, err := w.writeBlob(file.Content)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		dir := root
+		parts := strings.Split(file.Path, "/")
+		for _, part := range parts[:len(parts)-1] {
+			next, ok := dir.dirs[part]
+			if !ok {
+				next = newDirNode()
+				dir.dirs[part] = next
+			}
+			dir = next
+		}
+		dir.files[path.Base(file.Path)] = hash
+	}
+	return w.writeTree(root)
+}
+
+func (w *objectWriter) writeBlob(content string) (plumbing.Hash, error) {
+	if hash, ok := w.blobs[content]; ok {
+		return hash, nil
+	}
+	obj := w.s.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	obj.SetSize(int64(len(content)))
+	out, err := obj.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if _, err := io.WriteString(out, content); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if err := out.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	hash, err := w.s.SetEncodedObject(obj)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("writing blob: %w", err)
+	}
+	w.blobs[content] = hash
+	return hash, nil
+}
+
+// writeTree stores dir and the trees below it. Git orders entries by name
+// with directories compared as if they ended in a slash.
+func (w *objectWriter) writeTree(dir *dirNode) (plumbing.Hash, error) {
+	tree := &object.Tree{}
+	for name, hash := range dir.files {
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: name, Mode: filemode.Regular, Hash: hash})
+	}
+	for name, sub := range dir.dirs {
+		hash, err := w.writeTree(sub)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: name, Mode: filemode.Dir, Hash: hash})
+	}
+	sortKey := func(e object.TreeEntry) string {
+		if e.Mode == filemode.Dir {
+			return e.Name + "/"
+		}
+		return e.Name
+	}
+	sort.Slice(tree.Entries, func(i, j int) bool { return sortKey(tree.Entries[i]) < sortKey(tree.Entries[j]) })
+
+	obj := w.s.NewEncodedObject()
+	if err := tree.Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	hash, err := w.s.SetEncodedObject(obj)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("writing tree: %w", err)
+	}
+	return hash, nil
+}
+
+func (w *objectWriter) writeCommit(snapshot Snapshot, treeHash, parent plumbing.Hash) (plumbing.Hash, error) {
+	sig := object.Signature{Name: snapshot.Author.Name, Email: snapshot.Author.Email, When: snapshot.When}
+	commit := &object.Commit{
+		Author:    sig,
+		Committer: sig,
+		Message:   snapshot.Message + "\n",
+		TreeHash:  treeHash,
+	}
+	if parent != plumbing.ZeroHash {
+		commit.ParentHashes = []plumbing.Hash{parent}
+	}
+	obj := w.s.NewEncodedObject()
+	if err := commit.Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	hash, err := w.s.SetEncodedObject(obj)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("writing commit: %w", err)
+	}
+	return hash, nil
+}
+
+// bundleHeader starts a version 2 bundle, the format every git release
+// since 1.5 reads.
+const bundleHeader = "# v2 git bundle\n"
+
+func writeBundleFile(dest string, s storage.Storer, branch plumbing.ReferenceName, tip plumbing.Hash) error {
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := writeBundle(f, s, branch, tip); err != nil {
+		f.Close()
+		return fmt.Errorf("writing bundle %s: %w", dest, err)
+	}
+	return f.Close()
+}
+
+// writeBundle writes a complete bundle: a header listing branch and HEAD,
+// then a packfile with every object reachable from tip.
+func writeBundle(w io.Writer, s storage.Storer, branch plumbing.ReferenceName, tip plumbing.Hash) error {
+	hashes, err := revlist.Objects(s, []plumbing.Hash{tip}, nil)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprint(bw, bundleHeader)
+	fmt.Fprintf(bw, "%s %s\n", tip, branch)
+	fmt.Fprintf(bw, "%s %s\n\n", tip, plumbing.HEAD)
+	if _, err := packfile.NewEncoder(bw, s, false).Encode(hashes, 10); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
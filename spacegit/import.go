@@ -0,0 +1,193 @@
+package spacegit
+
+import (
+	"Hack4Change/spacearchive"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// DefaultRef is the revision Import reads when none is given.
+const DefaultRef = "HEAD"
+
+// ImportResult is the tree read from a commit.
+type ImportResult struct {
+	Tree spacearchive.Tree
+	// Commit is the hash ref resolved to.
+	Commit string
+}
+
+// Import reads the files of ref in the repository or bundle file at source.
+// ref is anything git rev-parse takes for a commit: a branch, a tag, a hash
+// or an expression such as main~2. Files go through the same checks as an
+// uploaded archive, so limits apply and binary files, symlinks and
+// submodules are skipped and listed.
+func Import(source, ref string, limits spacearchive.Limits) (ImportResult, error) {
+	if ref == "" {
+		ref = DefaultRef
+	}
+	repo, err := openSource(source)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("resolving %q in %s: %w", ref, source, err)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("reading commit %s: %w", hash, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("reading tree of %s: %w", hash, err)
+	}
+
+	b := spacearchive.NewBuilder(limits)
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+	for {
+		name, entry, err := walker.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return ImportResult{}, fmt.Errorf("reading tree of %s: %w", hash, err)
+		}
+		if err := b.Entry(); err != nil {
+			return ImportResult{}, err
+		}
+		switch entry.Mode {
+		case filemode.Dir:
+			err = b.AddDir(name)
+		case filemode.Regular, filemode.Executable, filemode.Deprecated:
+			err = addBlob(b, repo, name, entry.Hash, commit)
+		case filemode.Symlink:
+			err = b.SkipEntry(name, spacearchive.SkipSymlink)
+		default:
+			err = b.SkipEntry(name, spacearchive.SkipSpecial)
+		}
+		if err != nil {
+			return ImportResult{}, err
+		}
+	}
+	return ImportResult{Tree: b.Finish(), Commit: hash.String()}, nil
+}
+
+func addBlob(b *spacearchive.Builder, repo *git.Repository, name string, hash plumbing.Hash, commit *object.Commit) error {
+	blob, err := repo.BlobObject(hash)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", name, err)
+	}
+	r, err := blob.Reader()
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", name, err)
+	}
+	defer r.Close()
+	return b.AddFile(name, r, commit.Committer.When)
+}
+
+// openSource opens a repository directory, or loads a bundle file into
+// memory.
+func openSource(source string) (*git.Repository, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return readBundleFile(source)
+	}
+	repo, err := git.PlainOpen(source)
+	if err != nil {
+		return nil, fmt.Errorf("opening repository %s: %w", source, err)
+	}
+	return repo, nil
+}
+
+func readBundleFile(name string) (*git.Repository, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	repo, err := readBundle(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading bundle %s: %w", name, err)
+	}
+	return repo, nil
+}
+
+// readBundle loads a version 2 or 3 bundle. Bundles with prerequisites
+// were made by git bundle create with a range and lack the history they
+// build on, so they cannot be read on their own.
+func readBundle(r io.Reader) (*git.Repository, error) {
+	br := bufio.NewReader(r)
+	signature, err := br.ReadString('\n')
+	if err != nil {
+		return nil, errors.New("not a git bundle")
+	}
+	switch signature {
+	case "# v2 git bundle\n", "# v3 git bundle\n":
+	default:
+		return nil, errors.New("not a git bundle")
+	}
+
+	s := memory.NewStorage()
+	var refs []*plumbing.Reference
+	var head *plumbing.Reference
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, errors.New("bundle header is truncated")
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			break
+		}
+		switch {
+		case strings.HasPrefix(line, "@"):
+			if strings.HasPrefix(line, "@object-format=") && line != "@object-format=sha1" {
+				return nil, fmt.Errorf("unsupported bundle capability %s", line)
+			}
+		case strings.HasPrefix(line, "-"):
+			return nil, errors.New("bundle is incremental and needs commits it does not contain")
+		default:
+			hash, name, ok := strings.Cut(line, " ")
+			if !ok || !plumbing.IsHash(hash) {
+				return nil, fmt.Errorf("malformed bundle reference %q", line)
+			}
+			ref := plumbing.NewHashReference(plumbing.ReferenceName(name), plumbing.NewHash(hash))
+			if ref.Name() == plumbing.HEAD {
+				head = ref
+			} else {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	if head == nil {
+		if len(refs) == 0 {
+			return nil, errors.New("bundle has no references")
+		}
+		head = plumbing.NewSymbolicReference(plumbing.HEAD, refs[0].Name())
+	}
+
+	if err := packfile.UpdateObjectStorage(s, br); err != nil {
+		return nil, fmt.Errorf("reading packfile: %w", err)
+	}
+	for _, ref := range append(refs, head) {
+		if err := s.SetReference(ref); err != nil {
+			return nil, err
+		}
+	}
+	return git.Open(s, nil)
+}
@@ -0,0 +1,102 @@
+// Package spacegit converts between a space and a git repository: a space,
+// optionally with its revision history, is written as commits on a branch,
+// and a commit of a repository or bundle is read back as a space's tree.
+// Everything goes through go-git, so no git binary is needed.
+//
+// Git does not store empty directories, so empty folders are not exported.
+package spacegit
+
+import (
+	"Hack4Change/models"
+	"Hack4Change/spacearchive"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DefaultBranch is the branch exports write to when none is given.
+const DefaultBranch = "main"
+
+// Signature identifies the author of a commit.
+type Signature struct {
+	Name  string
+	Email string
+}
+
+// Snapshot is the state of a space to record as one commit.
+type Snapshot struct {
+	Files   []spacearchive.File
+	Message string
+	Author  Signature
+	When    time.Time
+}
+
+// History replays revisions, as returned by ListProjectRevisions, into one
+// snapshot per revision. Files keep their current paths throughout, since
+// renames and moves are not versioned. Revisions without an author are
+// attributed to fallback.
+func History(tree models.ProjectTree, revisions []models.ProjectRevision, fallback Signature) []Snapshot {
+	paths := filePaths(tree)
+	state := make(map[string]string, len(paths))
+	snapshots := make([]Snapshot, 0, len(revisions))
+	for _, revision := range revisions {
+		p, ok := paths[revision.FileID]
+		if !ok {
+			continue
+		}
+		state[p] = revision.Content
+
+		author := fallback
+		if revision.AuthorEmail != "" {
+			author = Signature{Name: revision.AuthorName, Email: revision.AuthorEmail}
+		}
+		snapshots = append(snapshots, Snapshot{
+			Files:   snapshotFiles(state),
+			Message: revisionMessage(p, revision),
+			Author:  author,
+			When:    revision.CreatedAt,
+		})
+	}
+	return snapshots
+}
+
+func revisionMessage(p string, revision models.ProjectRevision) string {
+	switch {
+	case revision.RestoredFrom != nil:
+		return "Restore " + p + " to version " + strconv.Itoa(*revision.RestoredFrom)
+	case revision.Version == 1:
+		return "Create " + p
+	}
+	return "Update " + p + " to version " + strconv.Itoa(revision.Version)
+}
+
+func snapshotFiles(state map[string]string) []spacearchive.File {
+	files := make([]spacearchive.File, 0, len(state))
+	for p, content := range state {
+		files = append(files, spacearchive.File{Path: p, Content: content})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
+
+// filePaths maps the ID of every file in tree to its path.
+func filePaths(tree models.ProjectTree) map[string]string {
+	paths := map[string]string{}
+	addFiles := func(dir string, files []models.File) {
+		for _, file := range files {
+			paths[file.ID] = path.Join(dir, file.FileName)
+		}
+	}
+	var walk func(dir string, folders []models.TreeFolder)
+	walk = func(dir string, folders []models.TreeFolder) {
+		for _, folder := range folders {
+			p := path.Join(dir, folder.FolderName)
+			addFiles(p, folder.Files)
+			walk(p, folder.Folders)
+		}
+	}
+	addFiles("", tree.Files)
+	walk("", tree.Folders)
+	return paths
+}
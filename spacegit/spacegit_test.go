@@ -0,0 +1,350 @@
+package spacegit
+
+import (
+	"Hack4Change/apperrors"
+	"Hack4Change/models"
+	"Hack4Change/spacearchive"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+var testAuthor = Signature{Name: "demo", Email: "demo@hack4change.dev"}
+
+var testLimits = spacearchive.Limits{MaxEntries: 100, MaxTotalBytes: 1 << 20, MaxFileBytes: 1 << 16}
+
+func snapshot(message string, files map[string]string) Snapshot {
+	s := Snapshot{Message: message, Author: testAuthor, When: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}
+	for p, content := range files {
+		s.Files = append(s.Files, spacearchive.File{Path: p, Content: content})
+	}
+	return s
+}
+
+// contents flattens an imported tree for comparison.
+func contents(tree spacearchive.Tree) map[string]string {
+	out := map[string]string{}
+	for _, file := range tree.Files {
+		out[file.Path] = file.Content
+	}
+	return out
+}
+
+func assertContents(t *testing.T, got spacearchive.Tree, want map[string]string) {
+	t.Helper()
+	have := contents(got)
+	if len(have) != len(want) {
+		t.Fatalf("got files %v, want %v", have, want)
+	}
+	for p, content := range want {
+		if have[p] != content {
+			t.Errorf("%s = %q, want %q", p, have[p], content)
+		}
+	}
+}
+
+func TestExportImportBareRepository(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "space.git")
+	files := map[string]string{
+		"README.md":         "# Space\n",
+		"src/main.py":       "print('hi')\n",
+		"src/lib/util.py":   "def util():\n    pass\n",
+		"src/lib/util.py.b": "sorted after the folder\n",
+	}
+	result, err := Export(dest, "", []Snapshot{snapshot("Export space", files)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Branch != DefaultBranch || result.Commits != 1 {
+		t.Fatalf("result = %+v", result)
+	}
+
+	repo, err := git.PlainOpen(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err := repo.Config(); err != nil || !cfg.Core.IsBare {
+		t.Fatalf("repository is not bare: %v", err)
+	}
+	head, err := repo.Head()
+	if err != nil || head.Name().Short() != DefaultBranch || head.Hash().String() != result.Commit {
+		t.Fatalf("HEAD = %v, %v", head, err)
+	}
+
+	imported, err := Import(dest, "", testLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Commit != result.Commit {
+		t.Errorf("imported %s, want %s", imported.Commit, result.Commit)
+	}
+	assertContents(t, imported.Tree, files)
+	wantDirs := []string{"src", "src/lib"}
+	if len(imported.Tree.Dirs) != len(wantDirs) || imported.Tree.Dirs[0] != wantDirs[0] || imported.Tree.Dirs[1] != wantDirs[1] {
+		t.Errorf("dirs = %v, want %v", imported.Tree.Dirs, wantDirs)
+	}
+}
+
+func TestExportSkipsUnchangedSnapshot(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "space.git")
+	files := map[string]string{"a.txt": "a\n"}
+	first, err := Export(dest, "", []Snapshot{snapshot("one", files)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Export(dest, "", []Snapshot{snapshot("two", files)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Commits != 0 || second.Commit != first.Commit {
+		t.Errorf("re-export wrote %d commits, tip %s, want 0 and %s", second.Commits, second.Commit, first.Commit)
+	}
+
+	third, err := Export(dest, "", []Snapshot{snapshot("three", map[string]string{"a.txt": "b\n"})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := git.PlainOpen(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := repo.CommitObject(plumbing.NewHash(third.Commit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commit.ParentHashes) != 1 || commit.ParentHashes[0].String() != first.Commit {
+		t.Errorf("parents = %v, want %s", commit.ParentHashes, first.Commit)
+	}
+}
+
+func TestExportRefusesPlainDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Export(dir, "", []Snapshot{snapshot("one", map[string]string{"a.txt": "a\n"})}); err == nil {
+		t.Fatal("exported into a directory that is not a repository")
+	}
+}
+
+// TestExportWorkingRepository exports into a repository with a work tree,
+// which may only gain branches it does not have checked out.
+func TestExportWorkingRepository(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshots := []Snapshot{snapshot("one", map[string]string{"a.txt": "a\n"})}
+	if _, err := Export(dir, "master", snapshots); err == nil {
+		t.Fatal("exported to the checked-out branch")
+	}
+
+	result, err := Export(dir, "export", snapshots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil || head.Target() != plumbing.Master {
+		t.Fatalf("HEAD = %v, %v; want it left on master", head, err)
+	}
+	imported, err := Import(dir, "export", testLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Commit != result.Commit {
+		t.Errorf("imported %s, want %s", imported.Commit, result.Commit)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	folder := "folder-1"
+	tree := models.ProjectTree{
+		Files: []models.File{{ID: "readme", FileName: "README.md"}},
+		Folders: []models.TreeFolder{{
+			ID: folder, FolderName: "src",
+			Files: []models.File{{ID: "main", FileName: "main.py", ParentFolderId: &folder}},
+		}},
+	}
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	restored := 1
+	revision := func(fileID string, version int, content, author string, restoredFrom *int) models.ProjectRevision {
+		at = at.Add(time.Minute)
+		r := models.ProjectRevision{AuthorName: author}
+		if author != "" {
+			r.AuthorEmail = author + "@example.com"
+		}
+		r.FileID, r.Version, r.Content, r.CreatedAt, r.RestoredFrom = fileID, version, content, at, restoredFrom
+		return r
+	}
+	revisions := []models.ProjectRevision{
+		revision("readme", 1, "v1\n", "alice", nil),
+		revision("main", 1, "print(1)\n", "bob", nil),
+		revision("readme", 2, "v2\n", "", nil),
+		revision("readme", 3, "v1\n", "alice", &restored),
+		revision("deleted", 1, "gone\n", "alice", nil),
+	}
+	snapshots := History(tree, revisions, testAuthor)
+	if len(snapshots) != 4 {
+		t.Fatalf("got %d snapshots, want 4", len(snapshots))
+	}
+	wantMessages := []string{"Create README.md", "Create src/main.py", "Update README.md to version 2", "Restore README.md to version 1"}
+	for i, want := range wantMessages {
+		if snapshots[i].Message != want {
+			t.Errorf("message %d = %q, want %q", i, snapshots[i].Message, want)
+		}
+	}
+	if snapshots[2].Author != testAuthor {
+		t.Errorf("revision without author attributed to %v", snapshots[2].Author)
+	}
+
+	dest := filepath.Join(t.TempDir(), "history.git")
+	result, err := Export(dest, "history", snapshots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Commits != 4 {
+		t.Fatalf("wrote %d commits, want 4", result.Commits)
+	}
+
+	repo, err := git.PlainOpen(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := repo.CommitObject(plumbing.NewHash(result.Commit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.Author.Name != "alice" || !commit.Author.When.Equal(revisions[3].CreatedAt) {
+		t.Errorf("tip author = %v", commit.Author)
+	}
+
+	for ref, want := range map[string]map[string]string{
+		"history":   {"README.md": "v1\n", "src/main.py": "print(1)\n"},
+		"history~1": {"README.md": "v2\n", "src/main.py": "print(1)\n"},
+		"history~3": {"README.md": "v1\n"},
+	} {
+		imported, err := Import(dest, ref, testLimits)
+		if err != nil {
+			t.Fatalf("%s: %v", ref, err)
+		}
+		assertContents(t, imported.Tree, want)
+	}
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "space.bundle")
+	files := map[string]string{"a.txt": "a\n", "dir/b.txt": "b\n"}
+	result, err := Export(dest, "export", []Snapshot{
+		snapshot("one", map[string]string{"a.txt": "a\n"}),
+		snapshot("two", files),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ref := range []string{"", "export", "refs/heads/export", result.Commit} {
+		imported, err := Import(dest, ref, testLimits)
+		if err != nil {
+			t.Fatalf("%q: %v", ref, err)
+		}
+		assertContents(t, imported.Tree, files)
+	}
+	imported, err := Import(dest, "export~1", testLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContents(t, imported.Tree, map[string]string{"a.txt": "a\n"})
+}
+
+func TestImportRejectsBadBundles(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"garbage.bundle":     "not a bundle\n",
+		"incremental.bundle": "# v2 git bundle\n-0123456789012345678901234567890123456789 base\n0123456789012345678901234567890123456789 refs/heads/main\n\n",
+		"empty.bundle":       "# v2 git bundle\n\n",
+	} {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Import(p, "", testLimits); err == nil {
+			t.Errorf("%s: imported", name)
+		}
+	}
+}
+
+// TestImportWorkingRepository imports from an ordinary repository made
+// with a work tree, where symlinks and binary files are skipped.
+func TestImportWorkingRepository(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, content string) {
+		t.Helper()
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("main.go", "package main\n")
+	write("assets/logo.png", "\x89PNG\r\n\x1a\n\x00\x00")
+	if err := os.Symlink("main.go", filepath.Join(dir, "link.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.AddGlob("."); err != nil {
+		t.Fatal(err)
+	}
+	sig := &object.Signature{Name: testAuthor.Name, Email: testAuthor.Email, When: time.Now()}
+	if _, err := wt.Commit("initial", &git.CommitOptions{Author: sig}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.Checkout(&git.CheckoutOptions{Branch: "refs/heads/feature", Create: true}); err != nil {
+		t.Fatal(err)
+	}
+	write("feature.go", "package feature\n")
+	if _, err := wt.Add("feature.go"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Commit("feature", &git.CommitOptions{Author: sig}); err != nil {
+		t.Fatal(err)
+	}
+
+	imported, err := Import(dir, "master", testLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContents(t, imported.Tree, map[string]string{"main.go": "package main\n"})
+	skipped := map[string]string{}
+	for _, entry := range imported.Tree.Skipped {
+		skipped[entry.Path] = entry.Reason
+	}
+	if skipped["assets/logo.png"] != spacearchive.SkipBinary || skipped["link.go"] != spacearchive.SkipSymlink {
+		t.Errorf("skipped = %v", imported.Tree.Skipped)
+	}
+
+	imported, err = Import(dir, "", testLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContents(t, imported.Tree, map[string]string{"main.go": "package main\n", "feature.go": "package feature\n"})
+
+	if _, err := Import(dir, "no-such-branch", testLimits); err == nil {
+		t.Error("imported a missing ref")
+	}
+	_, err = Import(dir, "", spacearchive.Limits{MaxEntries: 1})
+	if !apperrors.Is(err, apperrors.KindPayloadTooLarge) {
+		t.Errorf("entry limit: got %v", err)
+	}
+}
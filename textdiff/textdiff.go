@@ -0,0 +1,315 @@
+// Package textdiff produces line-based unified diffs.
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change,
+// as in diff -u.
+const DefaultContext = 3
+
+// Inputs with more lines than maxLines between them are diffed as one
+// replaced block without searching, and the search for a minimal diff
+// stops after maxWork diagonal steps, leaving what remains as replaced
+// blocks. Either way the diff is still correct, just not minimal, and
+// time and memory stay bounded by the input size.
+const (
+	maxLines = 200_000
+	maxWork  = 20_000_000
+)
+
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+type op struct {
+	kind opKind
+	line string
+}
+
+// Diff returns the unified diff turning a into b, with fromName and toName
+// in the --- and +++ headers, and counts the lines added and removed.
+// Equal inputs give an empty diff.
+func Diff(fromName, toName, a, b string, context int) (diff string, added, removed int) {
+	if a == b {
+		return "", 0, 0
+	}
+	ops := diffLines(splitLines(a), splitLines(b))
+	for _, o := range ops {
+		switch o.kind {
+		case opInsert:
+			added++
+		case opDelete:
+			removed++
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	for _, h := range hunks(ops, context) {
+		writeHunk(&sb, ops[h.start:h.end], h.aLine, h.bLine)
+	}
+	return sb.String(), added, removed
+}
+
+// splitLines splits s after every newline, keeping the terminators so a
+// missing final newline shows up as a difference.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func diffLines(a, b []string) []op {
+	d := &differ{a: a, b: b, ops: make([]op, 0, len(a)+len(b))}
+	if len(a)+len(b) > maxLines {
+		d.replace(0, len(a), 0, len(b))
+		return d.ops
+	}
+
+	// Lines are compared many times over, so compare small ids instead.
+	ids := make(map[string]int, len(a))
+	d.aIDs, d.bIDs = make([]int, len(a)), make([]int, len(b))
+	for i, line := range a {
+		id, ok := ids[line]
+		if !ok {
+			id = len(ids)
+			ids[line] = id
+		}
+		d.aIDs[i] = id
+	}
+	for i, line := range b {
+		id, ok := ids[line]
+		if !ok {
+			id = len(ids)
+			ids[line] = id
+		}
+		d.bIDs[i] = id
+	}
+	size := len(a) + len(b) + 3
+	d.forward, d.backward = make([]int, size), make([]int, size)
+	d.diff(0, len(a), 0, len(b))
+	return d.ops
+}
+
+// differ is the linear-space variant of the algorithm in "An O(ND)
+// Difference Algorithm and Its Variations": each step finds where a
+// shortest edit path crosses the middle by searching from both ends, then
+// the halves on either side are diffed the same way. The two V arrays are
+// shared by every step, since a step is done with them before recursing.
+type differ struct {
+	a, b              []string
+	aIDs, bIDs        []int
+	forward, backward []int
+	work              int
+	ops               []op
+}
+
+func (d *differ) diff(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.aIDs[aLo] == d.bIDs[bLo] {
+		d.ops = append(d.ops, op{opEqual, d.a[aLo]})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aHi-suffix > aLo && bHi-suffix > bLo && d.aIDs[aHi-1-suffix] == d.bIDs[bHi-1-suffix] {
+		suffix++
+	}
+
+	switch {
+	case aLo == aHi-suffix || bLo == bHi-suffix:
+		d.replace(aLo, aHi-suffix, bLo, bHi-suffix)
+	default:
+		if x, y, ok := d.split(aLo, aHi-suffix, bLo, bHi-suffix); ok {
+			d.diff(aLo, x, bLo, y)
+			d.diff(x, aHi-suffix, y, bHi-suffix)
+		} else {
+			d.replace(aLo, aHi-suffix, bLo, bHi-suffix)
+		}
+	}
+
+	for _, line := range d.a[aHi-suffix : aHi] {
+		d.ops = append(d.ops, op{opEqual, line})
+	}
+}
+
+// replace emits a[aLo:aHi] as deleted and b[bLo:bHi] as inserted.
+func (d *differ) replace(aLo, aHi, bLo, bHi int) {
+	for _, line := range d.a[aLo:aHi] {
+		d.ops = append(d.ops, op{opDelete, line})
+	}
+	for _, line := range d.b[bLo:bHi] {
+		d.ops = append(d.ops, op{opInsert, line})
+	}
+}
+
+// split finds a point on a shortest edit path from (aLo, bLo) to (aHi, bHi)
+// where the path can be cut in two. It reports false once the work budget
+// is spent. The ranges must be non-empty and differ in their first and
+// last lines.
+func (d *differ) split(aLo, aHi, bLo, bHi int) (x, y int, ok bool) {
+	n, m := aHi-aLo, bHi-bLo
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	size := 2*maxD + 2
+	vf, vb := d.forward[:size], d.backward[:size]
+	for i := range vf {
+		vf[i], vb[i] = -1, -1
+	}
+	vf[offset+1], vb[offset+1] = 0, 0
+
+	delta := n - m
+	// With an odd delta the paths can only meet on a forward step, and
+	// with an even one on a backward step.
+	front := delta%2 != 0
+	// Diagonals that have run off the edge of the grid are not searched
+	// again.
+	fStart, fEnd, bStart, bEnd := 0, 0, 0, 0
+	for step := 0; step < maxD; step++ {
+		if d.work > maxWork {
+			return 0, 0, false
+		}
+		for k := -step + fStart; k <= step-fEnd; k += 2 {
+			d.work++
+			i := offset + k
+			var fx int
+			if k == -step || (k != step && vf[i-1] < vf[i+1]) {
+				fx = vf[i+1]
+			} else {
+				fx = vf[i-1] + 1
+			}
+			fy := fx - k
+			for fx < n && fy < m && d.aIDs[aLo+fx] == d.bIDs[bLo+fy] {
+				fx++
+				fy++
+				d.work++
+			}
+			vf[i] = fx
+			switch {
+			case fx > n:
+				fEnd += 2
+			case fy > m:
+				fStart += 2
+			case front:
+				j := offset + delta - k
+				if j >= 0 && j < size && vb[j] != -1 && fx >= n-vb[j] {
+					return aLo + fx, bLo + fy, true
+				}
+			}
+		}
+
+		for k := -step + bStart; k <= step-bEnd; k += 2 {
+			d.work++
+			i := offset + k
+			var bx int
+			if k == -step || (k != step && vb[i-1] < vb[i+1]) {
+				bx = vb[i+1]
+			} else {
+				bx = vb[i-1] + 1
+			}
+			by := bx - k
+			for bx < n && by < m && d.aIDs[aHi-1-bx] == d.bIDs[bHi-1-by] {
+				bx++
+				by++
+				d.work++
+			}
+			vb[i] = bx
+			switch {
+			case bx > n:
+				bEnd += 2
+			case by > m:
+				bStart += 2
+			case !front:
+				j := offset + delta - k
+				if j >= 0 && j < size && vf[j] != -1 {
+					fx := vf[j]
+					fy := fx - (j - offset)
+					if fx >= n-bx {
+						return aLo + fx, bLo + fy, true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+type hunk struct {
+	start, end   int // range in ops
+	aLine, bLine int // 0-based line in a and b where the hunk starts
+}
+
+// hunks groups changes that are within 2*context lines of each other.
+func hunks(ops []op, context int) []hunk {
+	var out []hunk
+	aLine, bLine := 0, 0
+	var cur *hunk
+	lastChange := -1
+	for i, o := range ops {
+		if o.kind != opEqual {
+			if cur == nil || i-lastChange-1 > 2*context {
+				if cur != nil {
+					cur.end = min(lastChange+context+1, len(ops))
+					out = append(out, *cur)
+				}
+				start := max(i-context, 0)
+				cur = &hunk{start: start, aLine: aLine - (i - start), bLine: bLine - (i - start)}
+			}
+			lastChange = i
+		}
+		if o.kind != opInsert {
+			aLine++
+		}
+		if o.kind != opDelete {
+			bLine++
+		}
+	}
+	if cur != nil {
+		cur.end = min(lastChange+context+1, len(ops))
+		out = append(out, *cur)
+	}
+	return out
+}
+
+func writeHunk(sb *strings.Builder, ops []op, aStart, bStart int) {
+	aCount, bCount := 0, 0
+	for _, o := range ops {
+		if o.kind != opInsert {
+			aCount++
+		}
+		if o.kind != opDelete {
+			bCount++
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+	for _, o := range ops {
+		sb.WriteByte(byte(o.kind))
+		sb.WriteString(o.line)
+		if !strings.HasSuffix(o.line, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats a hunk side the way GNU diff does: an empty range
+// names the line before it, and a count of one is omitted.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,168 @@
+package textdiff
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name           string
+		a, b           string
+		context        int
+		want           string
+		added, removed int
+	}{
+		{
+			name: "equal",
+			a:    "a\nb\n", b: "a\nb\n",
+			context: 3,
+		},
+		{
+			name: "one changed line",
+			a:    "a\nb\nc\n", b: "a\nB\nc\n",
+			context: 3,
+			want:    "--- from\n+++ to\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+			added:   1, removed: 1,
+		},
+		{
+			name: "empty old side",
+			a:    "", b: "a\nb\n",
+			context: 3,
+			want:    "--- from\n+++ to\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+			added:   2,
+		},
+		{
+			name: "empty new side",
+			a:    "a\n", b: "",
+			context: 3,
+			want:    "--- from\n+++ to\n@@ -1 +0,0 @@\n-a\n",
+			removed: 1,
+		},
+		{
+			name: "missing final newline",
+			a:    "a\nb\n", b: "a\nb",
+			context: 3,
+			want:    "--- from\n+++ to\n@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n",
+			added:   1, removed: 1,
+		},
+		{
+			name: "separate hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n", b: "1\nX\n3\n4\n5\n6\n7\nY\n9\n",
+			context: 1,
+			want: "--- from\n+++ to\n@@ -1,3 +1,3 @@\n 1\n-2\n+X\n 3\n" +
+				"@@ -7,3 +7,3 @@\n 7\n-8\n+Y\n 9\n",
+			added: 2, removed: 2,
+		},
+		{
+			name: "close changes share a hunk",
+			a:    "1\n2\n3\n4\n5\n", b: "1\nX\n3\nY\n5\n",
+			context: 1,
+			want:    "--- from\n+++ to\n@@ -1,5 +1,5 @@\n 1\n-2\n+X\n 3\n-4\n+Y\n 5\n",
+			added:   2, removed: 2,
+		},
+		{
+			name: "insertion in the middle",
+			a:    "a\nb\nc\nd\n", b: "a\nb\nx\nc\nd\n",
+			context: 0,
+			want:    "--- from\n+++ to\n@@ -2,0 +3 @@\n+x\n",
+			added:   1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, added, removed := Diff("from", "to", tt.a, tt.b, tt.context)
+			if got != tt.want {
+				t.Errorf("diff:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if added != tt.added || removed != tt.removed {
+				t.Errorf("counted +%d -%d, want +%d -%d", added, removed, tt.added, tt.removed)
+			}
+		})
+	}
+}
+
+// TestDiffLinesMinimal checks random inputs against a dynamic programming
+// longest common subsequence: the ops must rebuild both sides and change
+// no more lines than needed.
+func TestDiffLinesMinimal(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	alphabet := []string{"a\n", "b\n", "c\n", "d\n"}
+	random := func() []string {
+		lines := make([]string, rng.Intn(30))
+		for i := range lines {
+			lines[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		return lines
+	}
+	for i := 0; i < 2000; i++ {
+		a, b := random(), random()
+		ops := diffLines(a, b)
+		checkRebuilds(t, a, b, ops)
+		changed := 0
+		for _, o := range ops {
+			if o.kind != opEqual {
+				changed++
+			}
+		}
+		if want := len(a) + len(b) - 2*lcs(a, b); changed != want {
+			t.Fatalf("diff of %q and %q changes %d lines, want %d", a, b, changed, want)
+		}
+	}
+}
+
+func TestDiffLinesFallback(t *testing.T) {
+	a := make([]string, maxLines/2+1)
+	for i := range a {
+		a[i] = "same\n"
+	}
+	b := append([]string{"new\n"}, a...)
+
+	ops := diffLines(a, b)
+	checkRebuilds(t, a, b, ops)
+	if len(ops) != len(a)+len(b) {
+		t.Errorf("got %d ops over maxLines, want every line replaced (%d)", len(ops), len(a)+len(b))
+	}
+
+	// A spent work budget leaves the rest as one replaced block too.
+	a, b = []string{"x\n", "a\n", "y\n"}, []string{"z\n", "a\n", "w\n"}
+	d := &differ{a: a, b: b, aIDs: []int{0, 1, 2}, bIDs: []int{3, 1, 4}, work: maxWork + 1,
+		forward: make([]int, 9), backward: make([]int, 9)}
+	d.diff(0, len(a), 0, len(b))
+	checkRebuilds(t, a, b, d.ops)
+	if len(d.ops) != len(a)+len(b) {
+		t.Errorf("got %v with no work budget left, want every line replaced", d.ops)
+	}
+}
+
+func checkRebuilds(t *testing.T, a, b []string, ops []op) {
+	t.Helper()
+	var gotA, gotB []string
+	for _, o := range ops {
+		if o.kind != opInsert {
+			gotA = append(gotA, o.line)
+		}
+		if o.kind != opDelete {
+			gotB = append(gotB, o.line)
+		}
+	}
+	if strings.Join(gotA, "") != strings.Join(a, "") || strings.Join(gotB, "") != strings.Join(b, "") {
+		t.Fatalf("ops %v do not rebuild %q and %q", ops, a, b)
+	}
+}
+
+func lcs(a, b []string) int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(cur[j], prev[j+1])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,208 @@
+package validation
+
+import (
+	"Hack4Change/apperrors"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/locales/en"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+)
+
+var trans ut.Translator
+
+// socialHosts lists the hosts accepted by the social_url validator for each
+// platform. Platforms without an entry accept any https URL.
+var socialHosts = map[string][]string{
+	"github":    {"github.com"},
+	"linkedin":  {"linkedin.com"},
+	"instagram": {"instagram.com"},
+}
+
+// Setup makes gin's binding validator honor the `validate` tags on our
+// models, registers the custom validators and loads English translations.
+// It must be called once before the router starts serving.
+func Setup() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("validation: unexpected gin validator engine")
+	}
+	v.SetTagName("validate")
+	v.RegisterTagNameFunc(jsonFieldName)
+
+	if err := v.RegisterValidation("safe_filename", validateSafeFilename); err != nil {
+		return err
+	}
+	if err := v.RegisterValidation("social_url", validateSocialURL); err != nil {
+		return err
+	}
+
+	english := en.New()
+	trans, _ = ut.New(english, english).GetTranslator("en")
+	if err := en_translations.RegisterDefaultTranslations(v, trans); err != nil {
+		return err
+	}
+	if err := registerTranslation(v, "safe_filename", "{0} must be a valid file name without slashes, control characters or reserved device names"); err != nil {
+		return err
+	}
+	if err := registerTranslation(v, "social_url", "{0} must be an https URL on {1}"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Error converts a binding error into a validation apperror with one entry
+// per offending JSON field.
+func Error(err error) *apperrors.Error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return TooLarge(maxBytesErr.Limit)
+	}
+
+	appErr := apperrors.Wrap(apperrors.KindValidation, err, "Invalid request")
+
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &validationErrs):
+		for _, fe := range validationErrs {
+			appErr.WithField(fieldPath(fe), translate(fe))
+		}
+	case errors.As(err, &typeErr):
+		appErr.WithField(typeErr.Field, fmt.Sprintf("must be of type %s", typeErr.Type.String()))
+	case errors.As(err, &syntaxErr):
+		appErr.Message = "Request body is not valid JSON"
+	}
+	return appErr
+}
+
+func translate(fe validator.FieldError) string {
+	if trans == nil {
+		return fe.Error()
+	}
+	return fe.Translate(trans)
+}
+
+// fieldPath strips the top-level struct name from the namespace so nested
+// fields come out as e.g. "social_accounts.github".
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.IndexByte(ns, '.'); i >= 0 {
+		return ns[i+1:]
+	}
+	return fe.Field()
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func registerTranslation(v *validator.Validate, tag, text string) error {
+	return v.RegisterTranslation(tag, trans,
+		func(ut ut.Translator) error {
+			return ut.Add(tag, text, true)
+		},
+		func(ut ut.Translator, fe validator.FieldError) string {
+			param := fe.Param()
+			if hosts, ok := socialHosts[param]; ok && tag == "social_url" {
+				param = hosts[0]
+			}
+			msg, _ := ut.T(tag, fe.Field(), param)
+			return msg
+		},
+	)
+}
+
+// validateSafeFilename rejects names that could escape their folder or break
+// archives and editors: path separators, dot names, control characters,
+// leading or trailing whitespace, Windows device names and names over
+// MaxFilenameLength.
+func validateSafeFilename(fl validator.FieldLevel) bool {
+	return IsSafeFilename(fl.Field().String())
+}
+
+// MaxFilenameLength is the longest file or folder name, in characters, that
+// the files and folders tables hold.
+const MaxFilenameLength = 255
+
+// reservedNames are Windows device names. A space exported or checked out on
+// Windows could not contain them, with or without an extension.
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// IsSafeFilename reports whether name is usable as a single file or folder
+// name inside a space.
+func IsSafeFilename(name string) bool {
+	if name == "" || name == "." || name == ".." {
+		return false
+	}
+	if utf8.RuneCountInString(name) > MaxFilenameLength {
+		return false
+	}
+	if strings.TrimSpace(name) != name {
+		return false
+	}
+	base, _, _ := strings.Cut(name, ".")
+	if reservedNames[strings.ToUpper(base)] {
+		return false
+	}
+	for _, r := range name {
+		if r == '/' || r == '\\' || unicode.IsControl(r) {
+			return false
+		}
+		if strings.ContainsRune(`<>:"|?*`, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateSocialURL checks that the value is an https URL on the platform
+// given as the tag parameter, e.g. `validate:"omitempty,social_url=github"`.
+func validateSocialURL(fl validator.FieldLevel) bool {
+	u, err := url.Parse(fl.Field().String())
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if u.Scheme != "https" {
+		return false
+	}
+	hosts, ok := socialHosts[fl.Param()]
+	if !ok {
+		return true
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range hosts {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// TooLarge is the error for a request body over limit bytes.
+func TooLarge(limit int64) *apperrors.Error {
+	return apperrors.PayloadTooLarge(fmt.Sprintf("Request body exceeds the %d byte limit", limit)).
+		WithField("body", fmt.Sprintf("must be at most %d bytes", limit))
+}
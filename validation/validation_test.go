@@ -0,0 +1,169 @@
+package validation
+
+import (
+	"Hack4Change/apperrors"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+type testSocials struct {
+	GitHub string `json:"github" validate:"omitempty,social_url=github"`
+	Other  string `json:"other" validate:"omitempty,social_url=other"`
+}
+
+type testPayload struct {
+	FileName string      `json:"file_name" validate:"required,safe_filename"`
+	Socials  testSocials `json:"social_accounts"`
+}
+
+func setup(t *testing.T) {
+	t.Helper()
+	if err := Setup(); err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+}
+
+func TestIsSafeFilename(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"main.go", true},
+		{"README", true},
+		{".gitignore", true},
+		{"notes..txt", true},
+		{"résumé.md", true},
+		{"console.log", true},
+		{"COM10", true},
+		{strings.Repeat("é", MaxFilenameLength), true},
+		{"", false},
+		{".", false},
+		{"..", false},
+		{"a/b", false},
+		{"../etc", false},
+		{`a\b`, false},
+		{"a\x00b", false},
+		{"tab\there", false},
+		{" leading", false},
+		{"trailing ", false},
+		{"what?", false},
+		{"a:b", false},
+		{"CON", false},
+		{"nul", false},
+		{"Aux.txt", false},
+		{"com1.tar.gz", false},
+		{"LPT9", false},
+		{strings.Repeat("a", MaxFilenameLength+1), false},
+		{strings.Repeat("é", MaxFilenameLength+1), false},
+	}
+	for _, tt := range tests {
+		if got := IsSafeFilename(tt.name); got != tt.want {
+			t.Errorf("IsSafeFilename(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSafeFilenameTag(t *testing.T) {
+	setup(t)
+	for _, name := range []string{"..", "a/b", "a\x00b", "PRN.md", strings.Repeat("x", MaxFilenameLength+1)} {
+		err := binding.Validator.ValidateStruct(testPayload{FileName: name})
+		if err == nil {
+			t.Errorf("file_name %q passed validation", name)
+			continue
+		}
+		fields := Error(err).Fields
+		if _, ok := fields["file_name"]; !ok || len(fields) != 1 {
+			t.Errorf("file_name %q: fields = %v, want only file_name", name, fields)
+		}
+	}
+	if err := binding.Validator.ValidateStruct(testPayload{FileName: "ok.txt"}); err != nil {
+		t.Errorf("file_name ok.txt: %v", err)
+	}
+}
+
+func TestSocialURLTag(t *testing.T) {
+	setup(t)
+	tests := []struct {
+		github, other string
+		valid         bool
+	}{
+		{github: "https://github.com/octocat", valid: true},
+		{github: "https://gist.github.com/octocat", valid: true},
+		{github: "https://GitHub.com/octocat", valid: true},
+		{other: "https://example.org/me", valid: true},
+		{valid: true},
+		{github: "http://github.com/octocat"},
+		{github: "github.com/octocat"},
+		{github: "https://evilgithub.com/octocat"},
+		{github: "https://github.com.evil.io/octocat"},
+		{github: "https://github.com/../../etc/passwd\x00"},
+		{github: "javascript:alert(1)"},
+		{other: "ftp://example.org/me"},
+	}
+	for _, tt := range tests {
+		err := binding.Validator.ValidateStruct(testPayload{
+			FileName: "ok.txt",
+			Socials:  testSocials{GitHub: tt.github, Other: tt.other},
+		})
+		if (err == nil) != tt.valid {
+			t.Errorf("github=%q other=%q: error = %v, want valid=%v", tt.github, tt.other, err, tt.valid)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	setup(t)
+	tests := []struct {
+		name       string
+		err        func() error
+		wantKind   apperrors.Kind
+		wantFields map[string]string
+	}{
+		{
+			name: "validation errors",
+			err: func() error {
+				return binding.Validator.ValidateStruct(testPayload{
+					Socials: testSocials{GitHub: "http://github.com/octocat"},
+				})
+			},
+			wantKind: apperrors.KindValidation,
+			wantFields: map[string]string{
+				"file_name":              "file_name is a required field",
+				"social_accounts.github": "github must be an https URL on github.com",
+			},
+		},
+		{
+			name:     "body too large",
+			err:      func() error { return &http.MaxBytesError{Limit: 1024} },
+			wantKind: apperrors.KindPayloadTooLarge,
+			wantFields: map[string]string{
+				"body": "must be at most 1024 bytes",
+			},
+		},
+		{
+			name: "wrong JSON type",
+			err: func() error {
+				var p testPayload
+				return json.Unmarshal([]byte(`{"file_name": 3}`), &p)
+			},
+			wantKind:   apperrors.KindValidation,
+			wantFields: map[string]string{"file_name": "must be of type string"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Error(tt.err())
+			if got.Kind != tt.wantKind {
+				t.Errorf("Kind = %v, want %v", got.Kind, tt.wantKind)
+			}
+			if !reflect.DeepEqual(got.Fields, tt.wantFields) {
+				t.Errorf("Fields = %v, want %v", got.Fields, tt.wantFields)
+			}
+		})
+	}
+}